@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// Collect addresses from a comma separated flag value and an optional file with one address per line
+// Blank lines and lines starting with # are ignored in the file
+func loadAddresses(list string, path string) ([]string, error) {
+	addresses := []string{}
+
+	for _, address := range strings.Split(list, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			addresses = append(addresses, line)
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, address := range addresses {
+		if !addressPattern.MatchString(address) {
+			return nil, fmt.Errorf("invalid address %q", address)
+		}
+	}
+
+	return addresses, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Print first, last, min, max and average base fee of the blocks that have one
+// Pre-London blocks have no base fee and are left out, without any London blocks nothing is printed
+func renderBaseFees(w io.Writer, headers []parser.Header) {
+	var first, last, min, max *big.Int
+	total := new(big.Int)
+	count := 0
+
+	for _, header := range headers {
+		fee := header.BaseFee
+		if fee == nil {
+			continue
+		}
+
+		if first == nil {
+			first = fee
+		}
+		last = fee
+		if min == nil || fee.Cmp(min) < 0 {
+			min = fee
+		}
+		if max == nil || fee.Cmp(max) > 0 {
+			max = fee
+		}
+
+		total.Add(total, fee)
+		count++
+	}
+
+	if count == 0 {
+		return
+	}
+
+	average := total.Quo(total, big.NewInt(int64(count)))
+
+	fmt.Fprintf(w, "Base fee (gwei):  first %s, last %s, min %s, max %s, average %s\n",
+		scaleDecimal(first, 9), scaleDecimal(last, 9), scaleDecimal(min, 9), scaleDecimal(max, 9), scaleDecimal(average, 9))
+}
+
+// Write the base fee of every block to path, pre-London blocks get empty amounts
+// Only blocks fetched by this run are listed, ones resumed from a checkpoint have no header
+func writeBaseFeeCSV(path string, headers []parser.Header) (err error) {
+	out, finish, err := openOutput(path)
+	if err != nil {
+		return err
+	}
+	defer func() { err = finish(err) }()
+
+	writer := csv.NewWriter(out)
+
+	if err := writer.Write([]string{"block", "base_fee_wei", "base_fee_gwei"}); err != nil {
+		return err
+	}
+
+	for _, header := range headers {
+		wei, gwei := "", ""
+		if header.BaseFee != nil {
+			wei, gwei = header.BaseFee.String(), scaleDecimal(header.BaseFee, 9)
+		}
+
+		if err := writer.Write([]string{header.Number.String(), wei, gwei}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
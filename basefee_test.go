@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/csv"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Two pre-London blocks and then base fees of 10, 30 and 20 gwei
+func baseFeeHeaders() []parser.Header {
+	headers := []parser.Header{{Number: big.NewInt(1)}, {Number: big.NewInt(2)}}
+	for i, fee := range gwei(10, 30, 20) {
+		headers = append(headers, parser.Header{Number: big.NewInt(int64(i + 3)), BaseFee: fee})
+	}
+	return headers
+}
+
+func TestBaseFeeTrend(t *testing.T) {
+	var out strings.Builder
+	renderBaseFees(&out, baseFeeHeaders())
+
+	want := "Base fee (gwei):  first 10, last 20, min 10, max 30, average 20\n"
+	if out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	// Nothing to say about a range from before London
+	out.Reset()
+	renderBaseFees(&out, baseFeeHeaders()[:2])
+	if out.Len() != 0 {
+		t.Errorf("printed %q for pre-London blocks", out.String())
+	}
+}
+
+func TestBaseFeeCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "basefee.csv")
+	if err := writeBaseFeeCSV(path, baseFeeHeaders()); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{"block", "base_fee_wei", "base_fee_gwei"},
+		{"1", "", ""},
+		{"2", "", ""},
+		{"3", "10000000000", "10"},
+		{"4", "30000000000", "30"},
+		{"5", "20000000000", "20"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("records %v", records)
+	}
+	for i := range want {
+		if strings.Join(records[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("line %d is %v, want %v", i+1, records[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// An EVM chain we know the GetBlock endpoint for
+type chain struct {
+	endpoint string
+
+	// Symbol of the native currency, only used for display
+	currency string
+}
+
+// Chains selectable with -chain
+// All of them use 18 decimals for the native currency, so the wei math is the same everywhere
+var chains = map[string]chain{
+	"mainnet": {endpoint: "https://eth.getblock.io/mainnet/", currency: "ETH"},
+	"goerli":  {endpoint: "https://eth.getblock.io/goerli/", currency: "ETH"},
+	"sepolia": {endpoint: "https://eth.getblock.io/sepolia/", currency: "ETH"},
+	"polygon": {endpoint: "https://matic.getblock.io/mainnet/", currency: "MATIC"},
+	"bsc":     {endpoint: "https://bsc.getblock.io/mainnet/", currency: "BNB"},
+}
+
+// Look up a chain by name, the error lists the valid names
+func lookupChain(name string) (chain, error) {
+	c, ok := chains[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(chains))
+		for name := range chains {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		return chain{}, fmt.Errorf("unknown -chain %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+
+	return c, nil
+}
+
+// Talk to GetBlock with the api keys from the flag or the environment, unless the user points us at their own node
+// The keys are never sent to a custom endpoint
+func selectEndpoint(selected chain, rpcURL, apiKeys string) (string, []string, error) {
+	if rpcURL != "" {
+		if apiKeys != "" {
+			return "", nil, errors.New("-api-keys cannot be combined with -rpc-url, keys are only sent to GetBlock")
+		}
+		return rpcURL, nil, nil
+	}
+
+	keys := resolveAPIKeys(apiKeys)
+	if keys == nil {
+		return "", nil, errors.New("no RPC endpoint: set GETBLOCK_API_KEY or pass -api-keys to use GetBlock, or pass -rpc-url to use your own node")
+	}
+
+	return selected.endpoint, keys, nil
+}
+
+// The name that keeps cached blocks, checkpoints and stored rows of different chains apart
+// A custom -rpc-url is only known by a hash of the URL, it may carry credentials and isn't a valid directory name
+func networkName(chainName, rpcURL string) string {
+	if rpcURL == "" {
+		return strings.ToLower(chainName)
+	}
+
+	sum := sha256.Sum256([]byte(rpcURL))
+	return "rpc-" + hex.EncodeToString(sum[:8])
+}
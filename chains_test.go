@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNetworkName(t *testing.T) {
+	if got := networkName("Polygon", ""); got != "polygon" {
+		t.Errorf("named chain got %q", got)
+	}
+
+	custom := networkName("mainnet", "https://node.example.com/secret-token")
+	if !strings.HasPrefix(custom, "rpc-") || strings.Contains(custom, "secret") {
+		t.Errorf("custom endpoint got %q, want a hash of the URL", custom)
+	}
+	if custom == networkName("mainnet", "https://other.example.com/") {
+		t.Error("two endpoints share a network name")
+	}
+}
+
+func TestLookupChain(t *testing.T) {
+	for name, want := range map[string]chain{
+		"mainnet": {endpoint: "https://eth.getblock.io/mainnet/", currency: "ETH"},
+		"Polygon": {endpoint: "https://matic.getblock.io/mainnet/", currency: "MATIC"},
+		"BSC":     {endpoint: "https://bsc.getblock.io/mainnet/", currency: "BNB"},
+	} {
+		got, err := lookupChain(name)
+		if err != nil || got != want {
+			t.Errorf("lookupChain(%s) = %+v, %v, want %+v", name, got, err, want)
+		}
+	}
+
+	// The error lists what would have worked
+	_, err := lookupChain("dogechain")
+	if err == nil || !strings.Contains(err.Error(), "bsc, goerli, mainnet, polygon, sepolia") {
+		t.Errorf("unknown chain got %v", err)
+	}
+}
+
+func TestSelectEndpoint(t *testing.T) {
+	mainnet := chains["mainnet"]
+	t.Setenv("GETBLOCK_API_KEYS", "")
+	t.Setenv("GETBLOCK_API_KEY", "env-key")
+
+	// -rpc-url wins over the chain, and the key in the environment stays at home
+	endpoint, keys, err := selectEndpoint(mainnet, "http://localhost:8545", "")
+	if err != nil || endpoint != "http://localhost:8545" || keys != nil {
+		t.Errorf("-rpc-url got %s, %v, %v", endpoint, keys, err)
+	}
+
+	endpoint, keys, err = selectEndpoint(mainnet, "", "")
+	if err != nil || endpoint != mainnet.endpoint || len(keys) != 1 || keys[0] != "env-key" {
+		t.Errorf("GetBlock got %s, %v, %v", endpoint, keys, err)
+	}
+
+	if _, _, err := selectEndpoint(mainnet, "http://localhost:8545", "flag-key"); err == nil {
+		t.Error("sent an API key to a custom endpoint")
+	}
+
+	t.Setenv("GETBLOCK_API_KEY", "")
+	if _, _, err := selectEndpoint(mainnet, "", ""); err == nil {
+		t.Error("picked GetBlock without a key")
+	}
+}
+
+func TestRPCURLRequestsCarryNoKey(t *testing.T) {
+	node, server := newFakeNode(t, 20)
+
+	opts := testOptions(t, server.URL)
+	opts.blocks = 2
+	runJSON(t, opts)
+
+	for _, key := range node.keys {
+		if key != "" {
+			t.Fatalf("a request to the custom endpoint carried the key %q", key)
+		}
+	}
+	if len(node.keys) == 0 {
+		t.Fatal("the custom endpoint got no requests")
+	}
+}
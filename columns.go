@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samsheff/getblocktz/report"
+)
+
+// One column of the results table, -columns picks them by name
+type tableColumn struct {
+	name   string
+	header func(u report.Unit) string
+	cell   func(rank int, r report.Row, u report.Unit) string
+
+	// Columns that only some rows fill, the default set leaves them out when no row does
+	filled func(r report.Row) bool
+}
+
+// Every column in the order of the default table
+var tableColumns = []tableColumn{
+	{name: "rank", header: func(report.Unit) string { return "#" }, cell: func(rank int, _ report.Row, _ report.Unit) string { return fmt.Sprintf("%d", rank) }},
+	{name: "address", header: func(report.Unit) string { return "Address" }, cell: func(_ int, r report.Row, _ report.Unit) string { return r.Address }},
+	{name: "type", header: func(report.Unit) string { return "Type" }, cell: func(_ int, r report.Row, _ report.Unit) string { return r.Kind }, filled: func(r report.Row) bool { return r.Kind != "" }},
+	{name: "name", header: func(report.Unit) string { return "Name" }, cell: func(_ int, r report.Row, _ report.Unit) string { return r.Name }, filled: func(r report.Row) bool { return r.Name != "" }},
+	{name: "label", header: func(report.Unit) string { return "Label" }, cell: func(_ int, r report.Row, _ report.Unit) string { return r.Label }, filled: func(r report.Row) bool { return r.Label != "" }},
+	{name: "sent", header: func(u report.Unit) string { return fmt.Sprintf("Sent (%s)", u.Label) }, cell: func(_ int, r report.Row, u report.Unit) string { return u.Format(r.Sent) }},
+	{name: "received", header: func(u report.Unit) string { return fmt.Sprintf("Received (%s)", u.Label) }, cell: func(_ int, r report.Row, u report.Unit) string { return u.Format(r.Received) }},
+	{name: "change", header: func(u report.Unit) string { return fmt.Sprintf("Total Change (%s)", u.Label) }, cell: func(_ int, r report.Row, u report.Unit) string { return u.Format(r.Change) }},
+	{name: "txcount", header: func(report.Unit) string { return "Tx Count" }, cell: func(_ int, r report.Row, _ report.Unit) string { return fmt.Sprintf("%d", r.TxCount) }},
+}
+
+// The names -columns accepts, for the usage and error messages
+func columnList() string {
+	names := make([]string, 0, len(tableColumns))
+	for _, column := range tableColumns {
+		names = append(names, column.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Parse the comma separated -columns list, in the order given
+// An empty list is nil, which stands for the default set
+func parseColumns(list string) ([]tableColumn, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+
+	return columnsByName(strings.Split(list, ","))
+}
+
+// The columns with the given names, in that order, nil names are the default set
+func columnsByName(names []string) ([]tableColumn, error) {
+	if names == nil {
+		return nil, nil
+	}
+
+	byName := make(map[string]tableColumn, len(tableColumns))
+	for _, column := range tableColumns {
+		byName[column.name] = column
+	}
+
+	var columns []tableColumn
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		column, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q in -columns, valid columns are %s", name, columnList())
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %q is listed twice in -columns", name)
+		}
+		seen[name] = true
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// The names of columns, for handing them to renderers, nil stays nil
+func columnNames(columns []tableColumn) []string {
+	if columns == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		names = append(names, column.name)
+	}
+	return names
+}
+
+// The default table, every column except the optional ones no row fills
+func defaultColumns(rows []report.Row) []tableColumn {
+	columns := make([]tableColumn, 0, len(tableColumns))
+	for _, column := range tableColumns {
+		if column.filled != nil && !anyFilled(rows, column.filled) {
+			continue
+		}
+		columns = append(columns, column)
+	}
+
+	return columns
+}
+
+func anyFilled(rows []report.Row, filled func(r report.Row) bool) bool {
+	for _, r := range rows {
+		if filled(r) {
+			return true
+		}
+	}
+	return false
+}
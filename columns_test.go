@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/report"
+)
+
+func TestParseColumnsKeepsTheGivenOrder(t *testing.T) {
+	columns, err := parseColumns(" Change, address ,txcount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(columnNames(columns), ","); got != "change,address,txcount" {
+		t.Errorf("got %s", got)
+	}
+
+	if columns, err := parseColumns(" "); columns != nil || err != nil {
+		t.Errorf("an empty list gave %v, %v, want the default set", columns, err)
+	}
+}
+
+func TestParseColumnsRejectsUnknownAndRepeatedNames(t *testing.T) {
+	_, err := parseColumns("address,balance")
+	if err == nil || !strings.Contains(err.Error(), `"balance"`) || !strings.Contains(err.Error(), columnList()) {
+		t.Errorf("got %v, want the bad name and the valid ones", err)
+	}
+
+	if _, err := parseColumns("address,change,address"); err == nil {
+		t.Error("a column listed twice was accepted")
+	}
+}
+
+func TestTableHasOnlyTheRequestedColumns(t *testing.T) {
+	columns, err := parseColumns("txcount,change,rank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []report.Row{{Address: alice, Label: "Alice", Change: big.NewInt(-5), Sent: big.NewInt(5), Received: new(big.Int), TxCount: 2}}
+	unit := report.Unit{Label: "wei", Format: func(wei *big.Int) string { return wei.String() }}
+
+	header, records := tableCells(rows, unit, columns, 1)
+	if got := fmt.Sprint(header); got != "[Tx Count Total Change (wei) #]" {
+		t.Errorf("header %s", got)
+	}
+	if got := fmt.Sprint(records); got != "[[2 -5 1]]" {
+		t.Errorf("records %s", got)
+	}
+
+	// The default set shows the label once a row has one
+	header, _ = tableCells(rows, unit, nil, 1)
+	if got := fmt.Sprint(header); got != "[# Address Label Sent (wei) Received (wei) Total Change (wei) Tx Count]" {
+		t.Errorf("default header %s", got)
+	}
+}
+
+func TestColumnsFlag(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	stdout, stderr, code := runMain(t, "-rpc-url", server.URL, "-blocks", "3", "-rps", "0", "-columns", "change,address")
+	if code != 0 {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+	if !regexp.MustCompile(`\|\s+TOTAL CHANGE \(ETH\)\s+\|\s+ADDRESS\s+\|\n`).MatchString(stdout) {
+		t.Errorf("not the requested columns\n%s", stdout)
+	}
+
+	_, stderr, code = runMain(t, "-rpc-url", server.URL, "-columns", "address,balance")
+	if code != 2 || !strings.Contains(stderr, "valid columns are") {
+		t.Errorf("exit code %d\n%s", code, stderr)
+	}
+}
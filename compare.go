@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ofen/getblock-go/eth"
+	"github.com/olekukonko/tablewriter"
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// One side of -compare, an inclusive block range
+type blockRange struct {
+	from, to *big.Int
+}
+
+func (r blockRange) String() string {
+	return fmt.Sprintf("%d-%d", r.from, r.to)
+}
+
+// Parse the value of -compare, two inclusive ranges like "1000-1099,1100-1199"
+func parseCompare(value string) ([]blockRange, error) {
+	sides := strings.Split(value, ",")
+	if len(sides) != 2 {
+		return nil, fmt.Errorf("-compare takes two ranges like 1000-1099,1100-1199, got %q", value)
+	}
+
+	ranges := make([]blockRange, 0, 2)
+	for _, side := range sides {
+		first, last, ok := strings.Cut(strings.TrimSpace(side), "-")
+		from, fromOK := new(big.Int).SetString(strings.TrimSpace(first), 10)
+		to, toOK := new(big.Int).SetString(strings.TrimSpace(last), 10)
+		if !ok || !fromOK || !toOK || from.Sign() < 0 || to.Cmp(from) < 0 {
+			return nil, fmt.Errorf("invalid -compare range %q, expected FROM-TO with FROM <= TO", side)
+		}
+		ranges = append(ranges, blockRange{from: from, to: to})
+	}
+
+	return ranges, nil
+}
+
+// One address of a comparison, an address missing from a range changed by zero there
+type compareRow struct {
+	address string
+	label   string
+	a, b    *big.Int
+	delta   *big.Int
+}
+
+// Scan both ranges of -compare one after the other and report how each address's change moved from A to B
+func compareRanges(ctx context.Context, opts options, client *eth.Client, config parser.Config) error {
+	if opts.dryRun {
+		for _, side := range opts.compare {
+			if err := printPlan(os.Stdout, side.from, side.to, config); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	head, err := latestBlock(ctx, client.Client, len(opts.apiKeys) > 0)
+	if err != nil {
+		return err
+	}
+	for _, side := range opts.compare {
+		if side.to.Cmp(head) > 0 {
+			return fmt.Errorf("-compare range %s is past the latest block (%d)", side, head)
+		}
+	}
+
+	// Once the scan is interrupted the second range is left with whatever it got, possibly nothing
+	start := time.Now()
+	results := make([]*parser.Result, 0, len(opts.compare))
+	partial := false
+	for _, side := range opts.compare {
+		result, err := parser.Scan(ctx, client.Client, side.from, side.to, config)
+		if err != nil && (ctx.Err() == nil || result == nil) {
+			return err
+		}
+		partial = partial || err != nil
+		if len(result.Failed) > 0 {
+			slog.Warn("totals are missing blocks", "range", side.String(), "failed", len(result.Failed), "which", result.Failed)
+		}
+		results = append(results, result)
+	}
+
+	if partial {
+		slog.Warn(stopReason(ctx) + ", the comparison only covers the blocks finished so far")
+
+		if opts.format == "table" && opts.out == "" && !opts.quiet {
+			fmt.Printf("PARTIAL RESULTS: %s, blocks that were not reached are missing\n", stopReason(ctx))
+		}
+	}
+
+	rows := buildCompareRows(results[0].Balances, results[1].Balances, opts.labels, opts.checksum)
+	rows = filterCompare(rows, opts.minWei)
+	sortCompare(rows, opts.descending)
+	if opts.top > 0 && len(rows) > opts.top {
+		rows = rows[:opts.top]
+	}
+
+	out, finish, err := openOutput(opts.out)
+	if err != nil {
+		return err
+	}
+	if err := finish(renderCompare(out, opts.format, opts.compare, rows, opts.unit)); err != nil {
+		return err
+	}
+
+	if opts.summary {
+		summaryOut := os.Stdout
+		if opts.format != "table" || opts.out != "" {
+			summaryOut = os.Stderr
+		}
+		fmt.Fprintf(summaryOut, "Addresses:        %d\n", len(rows))
+		if !opts.noMetadata {
+			fmt.Fprintf(summaryOut, "RPC calls:        %d\n", results[0].Calls+results[1].Calls)
+			fmt.Fprintf(summaryOut, "Duration:         %s\n", time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	if partial {
+		return errInterrupted
+	}
+	if opts.strict && (len(results[0].Failed) > 0 || len(results[1].Failed) > 0) {
+		return errIncomplete
+	}
+
+	return nil
+}
+
+// Every address that changed in either range, with both changes and the delta from A to B
+func buildCompareRows(a, b map[string]*big.Int, labels map[string]string, checksum bool) []compareRow {
+	rows := make([]compareRow, 0, len(a)+len(b))
+	change := func(balances map[string]*big.Int, address string) *big.Int {
+		if balance, ok := balances[address]; ok {
+			return balance
+		}
+		return new(big.Int)
+	}
+
+	add := func(address string) {
+		display := address
+		if checksum {
+			display = parser.ChecksumAddress(address)
+		}
+
+		r := compareRow{address: display, label: labels[address], a: change(a, address), b: change(b, address)}
+		r.delta = new(big.Int).Sub(r.b, r.a)
+		rows = append(rows, r)
+	}
+
+	for address := range a {
+		add(address)
+	}
+	for address := range b {
+		if _, ok := a[address]; !ok {
+			add(address)
+		}
+	}
+
+	return rows
+}
+
+// Drop addresses whose change moved by less than minWei either way
+func filterCompare(rows []compareRow, minWei *big.Int) []compareRow {
+	if minWei == nil || minWei.Sign() <= 0 {
+		return rows
+	}
+
+	kept := rows[:0]
+	for _, r := range rows {
+		if new(big.Int).Abs(r.delta).Cmp(minWei) >= 0 {
+			kept = append(kept, r)
+		}
+	}
+
+	return kept
+}
+
+// Order by delta, ties by address so the output is the same on every run
+func sortCompare(rows []compareRow, descending bool) {
+	sort.Slice(rows, func(i, j int) bool {
+		c := rows[i].delta.Cmp(rows[j].delta)
+		if descending {
+			c = -c
+		}
+		if c == 0 {
+			return rows[i].address < rows[j].address
+		}
+		return c < 0
+	})
+}
+
+type jsonCompare struct {
+	Address  string `json:"address"`
+	Label    string `json:"label,omitempty"`
+	AWei     string `json:"a_wei"`
+	BWei     string `json:"b_wei"`
+	DeltaWei string `json:"delta_wei"`
+}
+
+// Print the comparison, columns A and B are the change in each range and delta is B minus A
+func renderCompare(w io.Writer, format string, ranges []blockRange, rows []compareRow, u report.Unit) error {
+	switch format {
+	case "json", "ndjson":
+		encoder := json.NewEncoder(w)
+		records := make([]jsonCompare, 0, len(rows))
+		for _, r := range rows {
+			records = append(records, jsonCompare{Address: r.address, Label: r.label, AWei: r.a.String(), BWei: r.b.String(), DeltaWei: r.delta.String()})
+		}
+
+		if format == "ndjson" {
+			for _, record := range records {
+				if err := encoder.Encode(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"address", "label", "a_wei", "b_wei", "delta_wei"})
+		for _, r := range rows {
+			writer.Write([]string{r.address, r.label, r.a.String(), r.b.String(), r.delta.String()})
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+
+	if len(rows) == 0 {
+		_, err := fmt.Fprintf(w, "No balance changes found in blocks %s or %s\n", ranges[0], ranges[1])
+		return err
+	}
+
+	labelled := false
+	for _, r := range rows {
+		labelled = labelled || r.label != ""
+	}
+
+	header := []string{"Rank", "Address"}
+	if labelled {
+		header = append(header, "Label")
+	}
+	header = append(header,
+		fmt.Sprintf("A %s (%s)", ranges[0], u.Label),
+		fmt.Sprintf("B %s (%s)", ranges[1], u.Label),
+		fmt.Sprintf("Delta (%s)", u.Label))
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetAutoFormatHeaders(false)
+	for i, r := range rows {
+		record := []string{strconv.Itoa(i + 1), r.address}
+		if labelled {
+			record = append(record, r.label)
+		}
+		table.Append(append(record, u.Format(r.a), u.Format(r.b), u.Format(r.delta)))
+	}
+	table.Render()
+
+	return nil
+}
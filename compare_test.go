@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+func TestParseCompare(t *testing.T) {
+	ranges, err := parseCompare("1000-1099, 1100-1199")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranges) != 2 || ranges[0].String() != "1000-1099" || ranges[1].String() != "1100-1199" {
+		t.Errorf("got %v", ranges)
+	}
+
+	for _, value := range []string{"1000-1099", "1-2,3-4,5-6", "5-1,6-7", "a-b,1-2", "1-2,-3"} {
+		if _, err := parseCompare(value); err == nil {
+			t.Errorf("%q accepted", value)
+		}
+	}
+}
+
+func TestCompareRowsCoverAddressesOfEitherRange(t *testing.T) {
+	a := map[string]*big.Int{alice: big.NewInt(-10), bob: big.NewInt(10)}
+	b := map[string]*big.Int{bob: big.NewInt(4), carol: big.NewInt(-4)}
+
+	rows := buildCompareRows(a, b, nil, false)
+	sortCompare(rows, true)
+
+	// Alice only moved in A and carol only in B, each changed by zero in the other
+	want := []struct {
+		address     string
+		a, b, delta int64
+	}{
+		{alice, -10, 0, 10},
+		{carol, 0, -4, -4},
+		{bob, 10, 4, -6},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		r := rows[i]
+		if r.address != w.address || r.a.Int64() != w.a || r.b.Int64() != w.b || r.delta.Int64() != w.delta {
+			t.Errorf("row %d is %s %v %v %v, want %+v", i, r.address, r.a, r.b, r.delta, w)
+		}
+	}
+}
+
+func TestCompareScansBothRanges(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	var err error
+	opts := testOptions(t, server.URL)
+	if opts.compare, err = parseCompare("91-92,93-95"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runParser(context.Background(), opts, parser.Config{Workers: 2}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(opts.out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []jsonCompare
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatalf("%v in %s", err, data)
+	}
+
+	// Bob got 91 + 92 wei in A and 93 + 94 + 95 in B
+	want := []jsonCompare{
+		{Address: bob, AWei: "183", BWei: "282", DeltaWei: "99"},
+		{Address: alice, AWei: "-183", BWei: "-282", DeltaWei: "-99"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %+v", rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d is %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings can come from four places, anything earlier in this list wins
+const precedence = "Settings are taken from command line flags, then GOBLOCKPARSER_* environment variables\n" +
+	"(GOBLOCKPARSER_WORKERS for -workers, GOBLOCKPARSER_RPC_URL for -rpc-url), then the -config file, then the defaults below.\n" +
+	"GetBlock API keys come from -api-keys, then GETBLOCK_API_KEYS, then GETBLOCK_API_KEY."
+
+// Fill in every flag that wasn't given on the command line from its GOBLOCKPARSER_* variable
+// Flags set this way count as given, so the config file can't override them
+func applyEnv(flags *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] || err != nil {
+			return
+		}
+
+		name := envName(f.Name)
+		if value, ok := os.LookupEnv(name); ok {
+			if setErr := flags.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("%s: %w", name, setErr)
+			}
+		}
+	})
+
+	return err
+}
+
+// The GetBlock keys to rotate over, -api-keys wins over GETBLOCK_API_KEYS which wins over GETBLOCK_API_KEY
+// Blank entries are dropped, nil means there is no key at all
+func resolveAPIKeys(flagValue string) []string {
+	list := flagValue
+	if list == "" {
+		list = os.Getenv("GETBLOCK_API_KEYS")
+	}
+	if list == "" {
+		list = os.Getenv("GETBLOCK_API_KEY")
+	}
+
+	var keys []string
+	for _, key := range strings.Split(list, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// The environment variable for a flag, -rpc-url reads GOBLOCKPARSER_RPC_URL
+func envName(flagName string) string {
+	return "GOBLOCKPARSER_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Apply a YAML file of flag settings, keyed by flag name without the dash
+//
+//	blocks: 500
+//	chain: polygon
+//	addresses: [0xabc..., 0xdef...]
+//
+// Flags given on the command line or through the environment win over the file, unknown keys are all reported together
+func loadConfigFile(path string, flags *flag.FlagSet) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Scalars are taken as written, so addresses and big wei amounts aren't turned into numbers on the way
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	settings := map[string]*yaml.Node{}
+	if len(document.Content) > 0 {
+		root := document.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return fmt.Errorf("config file %s: expected a mapping of flag names to values", path)
+		}
+
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			settings[root.Content[i].Value] = root.Content[i+1]
+		}
+	}
+
+	explicit := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	unknown := []string{}
+	for _, key := range keys {
+		if key == "config" || flags.Lookup(key) == nil {
+			unknown = append(unknown, key)
+			continue
+		}
+
+		if explicit[key] {
+			continue
+		}
+
+		value, err := configValue(settings[key])
+		if err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+
+		if err := flags.Set(key, value); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("config file %s: unknown settings %s", path, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+// Turn a YAML value into the string the flag would get on the command line
+// Lists become comma separated, which is what the list flags like -addresses expect
+func configValue(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value, nil
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return "", fmt.Errorf("lists may only hold plain values")
+			}
+			items = append(items, item.Value)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("nested settings are not supported")
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testFlags() (*flag.FlagSet, *int, *string) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	workers := flags.Int("workers", 4, "")
+	rpcURL := flags.String("rpc-url", "", "")
+	return flags, workers, rpcURL
+}
+
+func TestEnvFillsAbsentFlags(t *testing.T) {
+	t.Setenv("GOBLOCKPARSER_WORKERS", "12")
+	t.Setenv("GOBLOCKPARSER_RPC_URL", "http://localhost:8545")
+
+	flags, workers, rpcURL := testFlags()
+	if err := flags.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+
+	if *workers != 12 || *rpcURL != "http://localhost:8545" {
+		t.Errorf("workers %d, rpc url %q", *workers, *rpcURL)
+	}
+}
+
+func TestFlagsWinOverEnv(t *testing.T) {
+	t.Setenv("GOBLOCKPARSER_WORKERS", "12")
+
+	flags, workers, _ := testFlags()
+	if err := flags.Parse([]string{"-workers", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+
+	if *workers != 2 {
+		t.Errorf("workers %d, want the flag's 2", *workers)
+	}
+}
+
+func TestEnvWinsOverConfigFile(t *testing.T) {
+	t.Setenv("GOBLOCKPARSER_WORKERS", "12")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("workers: 3\nrpc-url: http://node:8545\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	flags, workers, rpcURL := testFlags()
+	if err := flags.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfigFile(path, flags); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file still fills in what neither the flags nor the environment set
+	if *workers != 12 || *rpcURL != "http://node:8545" {
+		t.Errorf("workers %d, rpc url %q", *workers, *rpcURL)
+	}
+}
+
+func TestBadEnvValueNamesTheVariable(t *testing.T) {
+	t.Setenv("GOBLOCKPARSER_WORKERS", "many")
+
+	flags, _, _ := testFlags()
+	if err := flags.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyEnv(flags); err == nil || !strings.Contains(err.Error(), "GOBLOCKPARSER_WORKERS") {
+		t.Errorf("got %v, want an error naming GOBLOCKPARSER_WORKERS", err)
+	}
+}
+
+func TestAPIKeyPrecedence(t *testing.T) {
+	t.Setenv("GETBLOCK_API_KEYS", "")
+	t.Setenv("GETBLOCK_API_KEY", "")
+	if keys := resolveAPIKeys(""); keys != nil {
+		t.Errorf("no keys anywhere got %v", keys)
+	}
+
+	t.Setenv("GETBLOCK_API_KEY", "single")
+	if keys := resolveAPIKeys(""); !reflect.DeepEqual(keys, []string{"single"}) {
+		t.Errorf("GETBLOCK_API_KEY alone got %v", keys)
+	}
+
+	t.Setenv("GETBLOCK_API_KEYS", "one, two,")
+	if keys := resolveAPIKeys(""); !reflect.DeepEqual(keys, []string{"one", "two"}) {
+		t.Errorf("GETBLOCK_API_KEYS got %v", keys)
+	}
+
+	if keys := resolveAPIKeys("flag"); !reflect.DeepEqual(keys, []string{"flag"}) {
+		t.Errorf("-api-keys got %v", keys)
+	}
+}
+
+func TestConfigFileSetsFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	config := `workers: 16
+rpc-url: http://node:8545
+format: json
+tx-min-wei: 100000000000000000000000000
+addresses:
+  - 0x000000000000000000000000000000000000000A
+  - 0x000000000000000000000000000000000000000b
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	flags, workers, rpcURL := testFlags()
+	format := flags.String("format", "table", "")
+	minWei := flags.String("tx-min-wei", "", "")
+	addresses := flags.String("addresses", "", "")
+
+	// The command line wins over the file
+	if err := flags.Parse([]string{"-format", "csv"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadConfigFile(path, flags); err != nil {
+		t.Fatal(err)
+	}
+
+	if *workers != 16 || *rpcURL != "http://node:8545" || *format != "csv" {
+		t.Errorf("workers %d, rpc url %q, format %q", *workers, *rpcURL, *format)
+	}
+	// Taken as written, a YAML number would have lost digits and the casing of hex would be gone
+	if *minWei != "100000000000000000000000000" {
+		t.Errorf("tx-min-wei %q", *minWei)
+	}
+	if *addresses != "0x000000000000000000000000000000000000000A,0x000000000000000000000000000000000000000b" {
+		t.Errorf("addresses %q", *addresses)
+	}
+}
+
+func TestConfigFileRejectsUnknownAndNestedSettings(t *testing.T) {
+	for name, tc := range map[string]struct {
+		config string
+		want   string
+	}{
+		"unknown": {config: "workers: 2\nworker: 3\nblokcs: 10\n", want: "unknown settings blokcs, worker"},
+		"nested":  {config: "rpc-url:\n  url: http://node:8545\n", want: "rpc-url: nested settings are not supported"},
+		"list":    {config: "- workers\n", want: "expected a mapping"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tc.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			flags, _, _ := testFlags()
+			if err := flags.Parse(nil); err != nil {
+				t.Fatal(err)
+			}
+
+			err := loadConfigFile(path, flags)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got %v, want an error about %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// The page, script and styles of -dashboard, built into the binary so it runs from anywhere
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// How often a running scan reports its progress to the browser at most
+const progressInterval = 200 * time.Millisecond
+
+// The summary sent along with the rows once a dashboard scan is done
+type dashboardSummary struct {
+	From         *big.Int `json:"from"`
+	To           *big.Int `json:"to"`
+	Blocks       int      `json:"blocks"`
+	Failed       int      `json:"failed"`
+	Transactions int      `json:"transactions"`
+	Transfers    int      `json:"transfers"`
+	VolumeEth    string   `json:"volume_eth"`
+	Currency     string   `json:"currency"`
+	Addresses    int      `json:"addresses"`
+	Calls        int64    `json:"calls"`
+	Duration     string   `json:"duration"`
+}
+
+// The -serve endpoints plus the dashboard page and GET /scan/events, which streams a scan over server-sent events
+func (s *scanServer) DashboardHandler() http.Handler {
+	static, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scan", s.scan)
+	mux.HandleFunc("/scan/events", s.events)
+	mux.Handle("/", http.FileServer(http.FS(static)))
+	return mux
+}
+
+// Run the scan given by the from and to query parameters and stream it as server-sent events
+// "progress" events carry done and total blocks, then a single "result" event has the rows and summary
+// A scan that fails ends with a "failed" event instead
+func (s *scanServer) events(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	request := scanRequest{Format: "json"}
+	for name, target := range map[string]**big.Int{"from": &request.From, "to": &request.To} {
+		if value := r.URL.Query().Get(name); value != "" {
+			n, ok := new(big.Int).SetString(value, 10)
+			if !ok {
+				http.Error(w, fmt.Sprintf("invalid %s %q", name, value), http.StatusBadRequest)
+				return
+			}
+			*target = n
+		}
+	}
+
+	opts, config, err := s.settings(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts, config = dashboardSettings(opts, config)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		http.Error(w, "too many scans running, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	send := func(event string, payload interface{}) {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			slog.Warn("cannot encode dashboard event", "event", event, "err", err)
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+
+	// Progress is called from the aggregation loop, which runs right here in Scan, so writing to w is safe
+	var lastProgress time.Time
+	config.Progress = func(done, total int) {
+		if done < total && time.Since(lastProgress) < progressInterval {
+			return
+		}
+		lastProgress = time.Now()
+		send("progress", map[string]int{"done": done, "total": total})
+	}
+
+	start := time.Now()
+	result, err := parser.Scan(r.Context(), s.client, request.From, request.To, config)
+	if err != nil {
+		slog.Warn("scan failed", "from", request.From, "to", request.To, "err", err)
+		send("failed", map[string]string{"error": err.Error()})
+		return
+	}
+	took := time.Since(start)
+
+	var rows bytes.Buffer
+	if err := writeResults(r.Context(), &rows, opts, nil, result, took); err != nil {
+		send("failed", map[string]string{"error": err.Error()})
+		return
+	}
+
+	send("result", struct {
+		Rows    json.RawMessage  `json:"rows"`
+		Summary dashboardSummary `json:"summary"`
+	}{
+		Rows: rows.Bytes(),
+		Summary: dashboardSummary{
+			From:         request.From,
+			To:           request.To,
+			Blocks:       result.Blocks,
+			Failed:       len(result.Failed),
+			Transactions: result.Stats.Transactions,
+			Transfers:    result.Stats.Transfers,
+			VolumeEth:    exactEther(result.Stats.Volume),
+			Currency:     opts.currency,
+			Addresses:    result.Addresses,
+			Calls:        result.Calls,
+			Duration:     took.Round(time.Millisecond).String(),
+		},
+	})
+}
+
+// The page only shows balance rows, so the modes that replace them with another report are turned off
+func dashboardSettings(opts options, config parser.Config) (options, parser.Config) {
+	opts.format = "json"
+	opts.tokens, opts.between, opts.txCountsOnly, opts.countOnly = false, nil, false, false
+	config.Tokens, config.Between, config.TxCountsOnly, config.CountOnly = false, nil, false, false
+
+	return opts, config
+}
+
+// Serve the dashboard on opts.dashboard until ctx is cancelled, with the same limits as -serve
+func serveDashboard(ctx context.Context, opts options, client parser.Client, config parser.Config) error {
+	return listenAndServe(ctx, opts.dashboard, newScanServer(client, opts, config).DashboardHandler(), "serving the dashboard")
+}
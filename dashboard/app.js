@@ -0,0 +1,165 @@
+// Runs a scan through /scan/events and shows its progress, summary, chart and results as they come in
+var form = document.getElementById("scan");
+var progress = document.getElementById("progress");
+var statusLine = document.getElementById("status");
+var summary = document.getElementById("summary");
+var chart = document.getElementById("chart");
+var table = document.getElementById("results");
+var rows = [];
+
+form.addEventListener("submit", function (event) {
+  event.preventDefault();
+
+  var query = new URLSearchParams({ from: form.from.value, to: form.to.value });
+  var source = new EventSource("scan/events?" + query);
+  var finished = false;
+
+  form.querySelector("button").disabled = true;
+  progress.hidden = false;
+  progress.value = 0;
+  summary.hidden = chart.hidden = table.hidden = true;
+  setStatus("Scanning blocks " + form.from.value + " to " + form.to.value + "...", false);
+
+  function done() {
+    finished = true;
+    source.close();
+    form.querySelector("button").disabled = false;
+    progress.hidden = true;
+  }
+
+  source.addEventListener("progress", function (event) {
+    var p = JSON.parse(event.data);
+    progress.max = p.total;
+    progress.value = p.done;
+  });
+
+  source.addEventListener("result", function (event) {
+    var result = JSON.parse(event.data);
+    done();
+    setStatus(result.summary.failed > 0 ? result.summary.failed + " blocks could not be fetched and are missing from the totals" : "", result.summary.failed > 0);
+    showSummary(result.summary);
+    rows = result.rows.map(function (row, i) { row.rank = i + 1; return row; });
+    showChart(rows);
+    showRows(rows);
+  });
+
+  source.addEventListener("failed", function (event) {
+    done();
+    setStatus("Scan failed: " + JSON.parse(event.data).error, true);
+  });
+
+  // The server refused the scan or went away, EventSource would keep reconnecting otherwise
+  source.onerror = function () {
+    if (!finished) {
+      done();
+      setStatus("Scan failed, check the range and the server log", true);
+    }
+  };
+});
+
+function setStatus(text, error) {
+  statusLine.textContent = text;
+  statusLine.className = error ? "error" : "";
+}
+
+function showSummary(s) {
+  var items = [
+    ["Blocks", s.from + " to " + s.to + " (" + s.blocks + " blocks)"],
+    ["Transactions", s.transactions],
+    ["Value transfers", s.transfers],
+    ["Volume", s.volume_eth + " " + s.currency],
+    ["Addresses", s.addresses],
+    ["RPC calls", s.calls],
+    ["Duration", s.duration]
+  ];
+
+  summary.textContent = "";
+  items.forEach(function (item) {
+    var dt = document.createElement("dt");
+    var dd = document.createElement("dd");
+    dt.textContent = item[0];
+    dd.textContent = item[1];
+    summary.append(dt, dd);
+  });
+  summary.hidden = false;
+}
+
+// Bars for the ten biggest moves either way, gains above the line and losses below
+function showChart(rows) {
+  var top = rows.slice().sort(function (a, b) {
+    return Math.abs(Number(b.change_eth)) - Math.abs(Number(a.change_eth));
+  }).slice(0, 10);
+  if (top.length === 0) {
+    chart.hidden = true;
+    return;
+  }
+
+  var ctx = chart.getContext("2d");
+  var width = chart.width, height = chart.height, middle = height / 2;
+  var largest = Math.max.apply(null, top.map(function (row) { return Math.abs(Number(row.change_eth)); })) || 1;
+  var slot = width / top.length;
+
+  ctx.clearRect(0, 0, width, height);
+  ctx.font = "11px monospace";
+  ctx.textAlign = "center";
+  top.forEach(function (row, i) {
+    var value = Number(row.change_eth);
+    var bar = (Math.abs(value) / largest) * (middle - 20);
+    var x = i * slot + slot * 0.15;
+
+    ctx.fillStyle = value < 0 ? "#b00" : "#2a7";
+    ctx.fillRect(x, value < 0 ? middle : middle - bar, slot * 0.7, bar);
+    ctx.fillStyle = "#222";
+    ctx.fillText(row.label || row.address.slice(0, 8) + "...", x + slot * 0.35, value < 0 ? middle - 6 : middle + 14);
+  });
+  ctx.strokeStyle = "#999";
+  ctx.beginPath();
+  ctx.moveTo(0, middle);
+  ctx.lineTo(width, middle);
+  ctx.stroke();
+  chart.hidden = false;
+}
+
+function showRows(rows) {
+  var body = table.querySelector("tbody");
+  body.textContent = "";
+  rows.forEach(function (row) {
+    var tr = document.createElement("tr");
+    [
+      [row.rank, "num"],
+      [row.address, "address"],
+      [[row.label, row.ens_name, row.type ? "(" + row.type + ")" : ""].filter(Boolean).join(" "), ""],
+      [row.change_eth, row.change_wei.startsWith("-") ? "num negative" : "num"],
+      [row.tx_count, "num"]
+    ].forEach(function (cell) {
+      var td = document.createElement("td");
+      td.textContent = cell[0];
+      td.className = cell[1];
+      tr.appendChild(td);
+    });
+    body.appendChild(tr);
+  });
+  table.hidden = false;
+}
+
+// Clicking a column header sorts by it, amounts compare as BigInt so nothing is lost to rounding
+table.querySelectorAll("th").forEach(function (th) {
+  var ascending = false;
+  th.addEventListener("click", function () {
+    var key = th.dataset.key, numeric = th.hasAttribute("data-numeric");
+    ascending = !ascending;
+    rows.sort(function (a, b) {
+      var order;
+      if (key === "change_wei") {
+        var d = BigInt(a[key]) - BigInt(b[key]);
+        order = d > 0n ? 1 : d < 0n ? -1 : 0;
+      } else if (numeric) {
+        order = a[key] - b[key];
+      } else {
+        order = String(a[key] || "").localeCompare(String(b[key] || ""));
+      }
+      return ascending ? order : -order;
+    });
+    showRows(rows);
+  });
+});
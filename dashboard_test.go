@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestDashboard(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	s, _ := newTestScanServer(t)
+	server := httptest.NewServer(s.DashboardHandler())
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func get(t *testing.T, url string) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestDashboardPageLoads(t *testing.T) {
+	server := newTestDashboard(t)
+
+	resp, body := get(t, server.URL+"/")
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(body, "<title>getblocktz dashboard</title>") || !strings.Contains(body, `src="app.js"`) {
+		t.Errorf("not the dashboard page\n%s", body)
+	}
+
+	// The page's assets come from the binary as well
+	for _, asset := range []string{"/app.js", "/style.css"} {
+		if resp, body := get(t, server.URL+asset); resp.StatusCode != http.StatusOK || body == "" {
+			t.Errorf("%s: status %d with %d bytes", asset, resp.StatusCode, len(body))
+		}
+	}
+}
+
+// The events of a stream in order, "name" then its JSON data
+func readEvents(t *testing.T, r io.Reader) (names []string, data []string) {
+	t.Helper()
+
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		line := lines.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			names = append(names, name)
+		}
+		if payload, ok := strings.CutPrefix(line, "data: "); ok {
+			data = append(data, payload)
+		}
+	}
+	if len(names) != len(data) {
+		t.Fatalf("%d events with %d data lines", len(names), len(data))
+	}
+	return names, data
+}
+
+func TestDashboardStreamsAScan(t *testing.T) {
+	server := newTestDashboard(t)
+
+	resp, err := http.Get(server.URL + "/scan/events?from=1&to=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	names, data := readEvents(t, resp.Body)
+	if len(names) < 2 || names[len(names)-1] != "result" {
+		t.Fatalf("got events %v, want progress and then the result", names)
+	}
+	for _, name := range names[:len(names)-1] {
+		if name != "progress" {
+			t.Errorf("got events %v, want progress and then the result", names)
+		}
+	}
+
+	var result struct {
+		Rows    []jsonResult     `json:"rows"`
+		Summary dashboardSummary `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(data[len(data)-1]), &result); err != nil {
+		t.Fatal(err)
+	}
+	if got := received(t, result.Rows); got.Int64() != 6 {
+		t.Errorf("bob received %s, want 6", got)
+	}
+	if result.Summary.Blocks != 3 || result.Summary.Transactions != 3 || result.Summary.Addresses != 2 {
+		t.Errorf("summary %+v", result.Summary)
+	}
+}
+
+func TestDashboardRejectsBadScans(t *testing.T) {
+	server := newTestDashboard(t)
+
+	for query, want := range map[string]int{
+		"from=one&to=3": http.StatusBadRequest,
+		"from=1&to=50":  http.StatusBadRequest,
+	} {
+		if resp, _ := get(t, server.URL+"/scan/events?"+query); resp.StatusCode != want {
+			t.Errorf("%s: status %d, want %d", query, resp.StatusCode, want)
+		}
+	}
+
+	resp, err := http.Post(server.URL+"/scan/events", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status %d, want 405", resp.StatusCode)
+	}
+
+	// The JSON endpoint of -serve is there as well
+	if resp := postScan(t, server.URL, `{"from": 1, "to": 3}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("/scan: status %d", resp.StatusCode)
+	}
+}
+
+func TestDashboardIgnoresReportModes(t *testing.T) {
+	s, _ := newTestScanServer(t)
+	// As if started with -tokens -flow-from alice -flow-to bob
+	s.opts.tokens, s.opts.between = true, []string{alice, bob}
+	s.config.Tokens, s.config.Between = true, []string{alice, bob}
+	server := httptest.NewServer(s.DashboardHandler())
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/scan/events?from=1&to=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	names, data := readEvents(t, resp.Body)
+	if len(names) == 0 || names[len(names)-1] != "result" {
+		t.Fatalf("got events %v, want the result last", names)
+	}
+
+	var result struct {
+		Rows []jsonResult `json:"rows"`
+	}
+	if err := json.Unmarshal([]byte(data[len(data)-1]), &result); err != nil {
+		t.Fatal(err)
+	}
+	if got := received(t, result.Rows); got.Int64() != 6 {
+		t.Errorf("bob received %s, want 6 in the balance rows\n%s", got, data[len(data)-1])
+	}
+}
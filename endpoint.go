@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// Ask the node for its latest block before any worker starts
+// This is the first call of every run, so a bad key or an unreachable endpoint is reported here in plain words
+// rather than as a pile of failed blocks
+func latestBlock(ctx context.Context, client parser.Client, keyed bool) (*big.Int, error) {
+	r, err := client.Call(ctx, "eth_blockNumber")
+
+	var httpErr *jsonrpc.HTTPError
+	switch {
+	case errors.As(err, &httpErr) && (httpErr.Code == http.StatusUnauthorized || httpErr.Code == http.StatusForbidden):
+		if keyed {
+			return nil, fmt.Errorf("invalid or unauthorized API key, check GETBLOCK_API_KEY or -api-keys (HTTP %d)", httpErr.Code)
+		}
+		return nil, fmt.Errorf("the RPC endpoint requires authorization (HTTP %d)", httpErr.Code)
+	case httpErr != nil:
+		return nil, fmt.Errorf("the RPC endpoint answered with HTTP %d: %w", httpErr.Code, err)
+	case err != nil:
+		return nil, fmt.Errorf("cannot reach the RPC endpoint: %w", err)
+	case r.Error != nil:
+		// Some providers answer a bad key with a JSON-RPC error instead of an HTTP status
+		if keyed && isAuthMessage(r.Error.Message) {
+			return nil, fmt.Errorf("invalid or unauthorized API key, check GETBLOCK_API_KEY or -api-keys: %w", r.Error)
+		}
+		return nil, fmt.Errorf("the RPC endpoint rejected eth_blockNumber: %w", r.Error)
+	}
+
+	hex, ok := r.Result.(string)
+	head, valid := new(big.Int), false
+	if ok {
+		_, valid = head.SetString(hex, 0)
+	}
+	if !valid {
+		return nil, fmt.Errorf("the RPC endpoint answered eth_blockNumber with %v", r.Result)
+	}
+
+	return head, nil
+}
+
+func isAuthMessage(message string) bool {
+	message = strings.ToLower(message)
+	for _, hint := range []string{"unauthorized", "forbidden", "api key", "apikey", "access denied"} {
+		if strings.Contains(message, hint) {
+			return true
+		}
+	}
+
+	return false
+}
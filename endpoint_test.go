@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+func TestLatestBlockExplainsFailures(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	for name, tc := range map[string]struct {
+		status int
+		body   string
+		keyed  bool
+		url    string
+		want   string
+	}{
+		"bad key":         {status: http.StatusUnauthorized, keyed: true, want: "invalid or unauthorized API key"},
+		"forbidden key":   {status: http.StatusForbidden, keyed: true, want: "invalid or unauthorized API key"},
+		"no key":          {status: http.StatusUnauthorized, want: "the RPC endpoint requires authorization"},
+		"server error":    {status: http.StatusBadGateway, keyed: true, want: "answered with HTTP 502"},
+		"key in the body": {status: http.StatusOK, body: `{"jsonrpc":"2.0","id":0,"error":{"code":-32000,"message":"Invalid API key"}}`, keyed: true, want: "invalid or unauthorized API key"},
+		"rpc error":       {status: http.StatusOK, body: `{"jsonrpc":"2.0","id":0,"error":{"code":-32000,"message":"overloaded"}}`, keyed: true, want: "rejected eth_blockNumber"},
+		"unreachable":     {url: closed.URL, keyed: true, want: "cannot reach the RPC endpoint"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			url := tc.url
+			if url == "" {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(tc.status)
+					w.Write([]byte(tc.body))
+				}))
+				defer server.Close()
+				url = server.URL
+			}
+
+			_, err := latestBlock(context.Background(), newRPCClient(url, "", nil), tc.keyed)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got %v, want an error about %q", err, tc.want)
+			}
+		})
+	}
+}
+
+// A refused key stops the run at the first call, before any block is asked for
+func TestBadKeyStopsBeforeScanning(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	opts := testOptions(t, server.URL)
+	opts.apiKeys = []string{"expired"}
+
+	err := runParser(context.Background(), opts, parser.Config{Workers: 4})
+	if err == nil || !strings.Contains(err.Error(), "invalid or unauthorized API key") {
+		t.Errorf("got %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("made %d requests, want the one check", got)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// Print every transaction behind the total of the -explain address, with the running total after each one
+// The last running total is the address's total in the results
+func renderExplain(w io.Writer, address string, explained []parser.Contribution, u report.Unit, checksum bool) {
+	display := address
+	if checksum {
+		display = parser.ChecksumAddress(address)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Contributions to %s\n", display)
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Block", "Tx", fmt.Sprintf("Change (%s)", u.Label), fmt.Sprintf("Running Total (%s)", u.Label)})
+	table.SetAutoWrapText(false)
+
+	total := new(big.Int)
+	for _, contribution := range explained {
+		total.Add(total, contribution.Amount)
+		table.Append([]string{contribution.Block.String(), contribution.Hash, u.Format(contribution.Amount), u.Format(total)})
+	}
+
+	table.SetFooter([]string{"", strconv.Itoa(len(explained)) + " changes", "Total", u.Format(total)})
+	table.Render()
+}
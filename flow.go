@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Net ETH moved between the two addresses of -flow-from and -flow-to
+type pairFlow struct {
+	from, to     string
+	forward      *big.Int
+	back         *big.Int
+	net          *big.Int
+	transactions int
+}
+
+// Work out the flow between from and to, the scan must have been restricted to transactions between the two
+// so whatever one of them received came from the other
+func buildFlow(from, to string, flows map[string]*parser.Flow, checksum bool) pairFlow {
+	received := func(address string) *big.Int {
+		if flow, ok := flows[address]; ok {
+			return flow.Received
+		}
+		return new(big.Int)
+	}
+
+	f := pairFlow{from: from, to: to, forward: received(to), back: received(from)}
+	f.net = new(big.Int).Sub(f.forward, f.back)
+	if flow, ok := flows[from]; ok {
+		f.transactions = flow.Transactions
+	}
+
+	if checksum {
+		f.from, f.to = parser.ChecksumAddress(from), parser.ChecksumAddress(to)
+	}
+
+	return f
+}
+
+type jsonFlow struct {
+	From         string `json:"from"`
+	To           string `json:"to"`
+	ForwardWei   string `json:"from_to_wei"`
+	BackWei      string `json:"to_from_wei"`
+	NetWei       string `json:"net_wei"`
+	NetETH       string `json:"net_eth"`
+	Transactions int    `json:"transactions"`
+}
+
+// Print the flow in the selected format, a positive net means from paid to more than it got back
+func renderFlow(w io.Writer, format string, f pairFlow, currency string, decimals int) error {
+	switch format {
+	case "json", "ndjson":
+		encoder := json.NewEncoder(w)
+		if format == "json" {
+			encoder.SetIndent("", "  ")
+		}
+
+		return encoder.Encode(jsonFlow{
+			From:         f.from,
+			To:           f.to,
+			ForwardWei:   f.forward.String(),
+			BackWei:      f.back.String(),
+			NetWei:       f.net.String(),
+			NetETH:       exactEther(f.net),
+			Transactions: f.transactions,
+		})
+	case "csv":
+		writer := csv.NewWriter(w)
+
+		if err := writer.Write([]string{"from", "to", "from_to_wei", "to_from_wei", "net_wei", "net_eth", "transactions"}); err != nil {
+			return err
+		}
+		if err := writer.Write([]string{f.from, f.to, f.forward.String(), f.back.String(), f.net.String(), exactEther(f.net), strconv.Itoa(f.transactions)}); err != nil {
+			return err
+		}
+
+		writer.Flush()
+
+		return writer.Error()
+	}
+
+	fmt.Fprintf(w, "Sent:     %s %s (%s -> %s)\n", formatEther(f.forward, decimals), currency, f.from, f.to)
+	fmt.Fprintf(w, "Returned: %s %s (%s -> %s)\n", formatEther(f.back, decimals), currency, f.to, f.from)
+	fmt.Fprintf(w, "Net:      %s %s over %d transactions\n", formatEther(f.net, decimals), currency, f.transactions)
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// The flows of a scan restricted to alice and bob, alice sent 10 wei over and got 3 back in two transactions
+func pairFlows() map[string]*parser.Flow {
+	return map[string]*parser.Flow{
+		alice: {Sent: big.NewInt(10), Received: big.NewInt(3), Transactions: 2},
+		bob:   {Sent: big.NewInt(3), Received: big.NewInt(10), Transactions: 2},
+	}
+}
+
+func TestBuildFlowNetsBothDirections(t *testing.T) {
+	f := buildFlow(alice, bob, pairFlows(), false)
+	if f.forward.Int64() != 10 || f.back.Int64() != 3 || f.net.Int64() != 7 || f.transactions != 2 {
+		t.Errorf("flow %+v", f)
+	}
+
+	// Asked the other way around the net flips sign
+	if f := buildFlow(bob, alice, pairFlows(), false); f.net.Int64() != -7 {
+		t.Errorf("reverse net %s, want -7", f.net)
+	}
+
+	// Nothing moved between them at all
+	if f := buildFlow(alice, bob, map[string]*parser.Flow{}, false); f.net.Sign() != 0 || f.transactions != 0 {
+		t.Errorf("empty flow %+v", f)
+	}
+}
+
+func TestFlowAsJSON(t *testing.T) {
+	var out strings.Builder
+	if err := renderFlow(&out, "json", buildFlow(alice, bob, pairFlows(), false), "ETH", 4); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded jsonFlow
+	if err := json.Unmarshal([]byte(out.String()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ForwardWei != "10" || decoded.BackWei != "3" || decoded.NetWei != "7" || decoded.NetETH != "0.000000000000000007" {
+		t.Errorf("flow %+v", decoded)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/samsheff/getblocktz/report"
+)
+
+// The built in formats, in the order they are listed in the usage
+func init() {
+	// A table with nothing but a header looks like the scan went wrong, so say so instead
+	report.Register("table", "text/plain; charset=utf-8", false, report.RendererFunc(func(w io.Writer, rows []report.Row, info report.Info) error {
+		if len(rows) == 0 {
+			_, err := fmt.Fprintln(w, noChanges(info.Result))
+			return err
+		}
+		columns, err := columnsByName(info.Columns)
+		if err != nil {
+			return err
+		}
+		renderTable(w, rows, info.Unit, columns)
+		return nil
+	}))
+	report.Register("markdown", "text/markdown; charset=utf-8", false, report.RendererFunc(func(w io.Writer, rows []report.Row, info report.Info) error {
+		if len(rows) == 0 {
+			_, err := fmt.Fprintln(w, noChanges(info.Result))
+			return err
+		}
+		columns, err := columnsByName(info.Columns)
+		if err != nil {
+			return err
+		}
+		renderMarkdown(w, rows, info.Unit, columns)
+		return nil
+	}))
+	report.Register("html", "text/html; charset=utf-8", false, report.RendererFunc(func(w io.Writer, rows []report.Row, info report.Info) error {
+		return renderHTML(w, rows, info.Unit, info.Result, info.Took, info.Currency, info.Decimals, info.Metadata)
+	}))
+	report.Register("json", "application/json", true, report.RendererFunc(func(w io.Writer, rows []report.Row, info report.Info) error {
+		return renderJSON(w, rows)
+	}))
+	report.Register("ndjson", "application/x-ndjson", true, report.RendererFunc(func(w io.Writer, rows []report.Row, info report.Info) error {
+		return renderNDJSON(w, rows)
+	}))
+	report.Register("csv", "text/csv; charset=utf-8", true, report.RendererFunc(func(w io.Writer, rows []report.Row, info report.Info) error {
+		return renderCSV(w, rows)
+	}))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// A renderer registered from outside the built in set is picked by -format like any other
+func TestWriteResultsUsesRegisteredRenderer(t *testing.T) {
+	report.Register("test-addresses", "text/plain", false, report.RendererFunc(func(w io.Writer, rows []report.Row, info report.Info) error {
+		for _, r := range rows {
+			if _, err := io.WriteString(w, r.Address+" "+info.Unit.Format(r.Change)+"\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	u, err := lookupUnit("wei", "ETH", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := options{format: "test-addresses", unit: u, sortKey: "net", descending: true}
+	result := &parser.Result{Balances: map[string]*big.Int{
+		"0x000000000000000000000000000000000000000a": big.NewInt(-5),
+		"0x000000000000000000000000000000000000000b": big.NewInt(5),
+	}}
+
+	var out strings.Builder
+	if err := writeResults(context.Background(), &out, opts, nil, result, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "0x000000000000000000000000000000000000000b 5\n0x000000000000000000000000000000000000000a -5\n"
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestBuiltInFormatsAreRegistered(t *testing.T) {
+	for _, name := range []string{"table", "markdown", "html", "json", "ndjson", "csv"} {
+		if _, ok := report.Lookup(name); !ok {
+			t.Errorf("%s is not registered", name)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Distribution of one kind of gas price over a scan, all in wei per gas
+type gasSummary struct {
+	count                       int
+	min, median, mean, p90, max *big.Int
+}
+
+// Summarize a list of prices, false when there are none
+func summarizeGas(prices []*big.Int) (gasSummary, bool) {
+	if len(prices) == 0 {
+		return gasSummary{}, false
+	}
+
+	sorted := append([]*big.Int(nil), prices...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+
+	total := new(big.Int)
+	for _, price := range sorted {
+		total.Add(total, price)
+	}
+
+	return gasSummary{
+		count:  len(sorted),
+		min:    sorted[0],
+		median: percentile(sorted, 50),
+		mean:   total.Quo(total, big.NewInt(int64(len(sorted)))),
+		p90:    percentile(sorted, 90),
+		max:    sorted[len(sorted)-1],
+	}, true
+}
+
+// Nearest rank percentile of an ascending list, always one of the actual values
+func percentile(sorted []*big.Int, p int) *big.Int {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+// Print the gas price distributions as a small table in gwei
+// Max fee and priority fee rows only show up when the range had EIP-1559 transactions
+func renderGasStats(w io.Writer, gas parser.GasPrices) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Gas (gwei)", "Transactions", "Min", "Median", "Mean", "P90", "Max"})
+
+	for _, kind := range []struct {
+		name   string
+		prices []*big.Int
+	}{
+		{"Gas price", gas.Price},
+		{"Max fee", gas.MaxFee},
+		{"Max priority fee", gas.MaxPriorityFee},
+	} {
+		summary, ok := summarizeGas(kind.prices)
+		if !ok {
+			continue
+		}
+
+		table.Append([]string{
+			kind.name,
+			strconv.Itoa(summary.count),
+			scaleDecimal(summary.min, 9),
+			scaleDecimal(summary.median, 9),
+			scaleDecimal(summary.mean, 9),
+			scaleDecimal(summary.p90, 9),
+			scaleDecimal(summary.max, 9),
+		})
+	}
+
+	fmt.Fprintln(w)
+	table.Render()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Whole gwei amounts in wei
+func gwei(amounts ...int64) []*big.Int {
+	prices := []*big.Int{}
+	for _, amount := range amounts {
+		prices = append(prices, new(big.Int).Mul(big.NewInt(amount), big.NewInt(1e9)))
+	}
+	return prices
+}
+
+func TestSummarizeGas(t *testing.T) {
+	// 1 to 10 gwei, out of order
+	summary, ok := summarizeGas(gwei(7, 3, 10, 1, 9, 2, 8, 4, 6, 5))
+	if !ok {
+		t.Fatal("no summary")
+	}
+
+	// Nearest rank, so the median of ten is the 5th value and p90 the 9th
+	for name, got := range map[string]*big.Int{"min": summary.min, "median": summary.median, "mean": summary.mean, "p90": summary.p90, "max": summary.max} {
+		want := map[string]string{"min": "1", "median": "5", "mean": "5.5", "p90": "9", "max": "10"}[name]
+		if scaleDecimal(got, 9) != want {
+			t.Errorf("%s is %s gwei, want %s", name, scaleDecimal(got, 9), want)
+		}
+	}
+	if summary.count != 10 {
+		t.Errorf("count %d", summary.count)
+	}
+
+	if _, ok := summarizeGas(nil); ok {
+		t.Error("summarized no prices")
+	}
+}
+
+func TestPercentileOfFewValues(t *testing.T) {
+	for _, tc := range []struct {
+		prices []*big.Int
+		p      int
+		want   int64
+	}{
+		{gwei(4), 50, 4},
+		{gwei(4), 90, 4},
+		{gwei(1, 2), 50, 1},
+		{gwei(1, 2), 90, 2},
+		{gwei(1, 2, 3), 50, 2},
+	} {
+		if got := percentile(tc.prices, tc.p); got.Cmp(gwei(tc.want)[0]) != 0 {
+			t.Errorf("p%d of %d prices is %s, want %d gwei", tc.p, len(tc.prices), got, tc.want)
+		}
+	}
+}
+
+// Legacy transactions only have a gas price, so the EIP-1559 rows stay out of the table
+func TestGasStatsTableSkipsMissingKinds(t *testing.T) {
+	var out strings.Builder
+	renderGasStats(&out, parser.GasPrices{Price: gwei(1, 2, 3)})
+
+	if !strings.Contains(out.String(), "Gas price") || strings.Contains(out.String(), "Max fee") {
+		t.Errorf("table\n%s", out.String())
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/samsheff/getblocktz/rpc"
+	"google.golang.org/grpc"
+)
+
+// Serve the BlockParser gRPC service on opts.grpcAddr until ctx is cancelled, with the same limits as -serve
+// Running scans get to finish their stream, new calls are refused once shutdown starts
+func serveGRPC(ctx context.Context, opts options, client parser.Client, config parser.Config) error {
+	listener, err := net.Listen("tcp", opts.grpcAddr)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewServer()
+	rpc.RegisterBlockParserServer(server, rpc.NewServer(client, config, rpc.Limits{MaxBlocks: int64(opts.maxBlocks), MaxScans: opts.maxScans}))
+
+	stop := context.AfterFunc(ctx, server.GracefulStop)
+	defer stop()
+
+	slog.Info("serving gRPC", "addr", listener.Addr().String())
+
+	return server.Serve(listener)
+}
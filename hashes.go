@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/ofen/getblock-go/eth"
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Scan the blocks given with -block-hashes and report them like a range
+// There is no head to look up and no range to save, the rest follows runParser
+func scanHashes(ctx context.Context, opts options, client *eth.Client, config parser.Config) error {
+	if opts.dryRun {
+		fmt.Printf("Blocks:         %d, by hash\n", len(opts.hashes))
+		fmt.Printf("Workers:        %d\n", config.Workers)
+		fmt.Printf("Block requests: %d\n", len(opts.hashes))
+		return nil
+	}
+
+	// The head itself isn't needed, but this still tells a bad key apart from missing blocks
+	if _, err := latestBlock(ctx, client.Client, len(opts.apiKeys) > 0); err != nil {
+		return err
+	}
+
+	var bar *progress
+	if opts.progress {
+		bar = newProgress(os.Stderr)
+		config.Progress = bar.update
+	}
+
+	start := time.Now()
+	result, err := parser.ScanHashes(ctx, client.Client, opts.hashes, config)
+	bar.finish()
+	took := time.Since(start)
+
+	partial := err != nil && ctx.Err() != nil && result != nil
+	if err != nil && !partial {
+		return err
+	}
+
+	if len(result.FailedHashes) > 0 {
+		slog.Warn("totals are missing blocks", "failed", len(result.FailedHashes), "blocks", result.Blocks, "which", result.FailedHashes)
+	}
+
+	if partial {
+		slog.Warn(stopReason(ctx) + ", the results only cover the blocks finished so far")
+		if err := printReport(ctx, opts, nil, result, took); err != nil {
+			return err
+		}
+		return errInterrupted
+	}
+
+	lookup := newLookups(client.Client, config, opts)
+
+	if err := printReport(ctx, opts, lookup, result, took); err != nil {
+		return err
+	}
+
+	if opts.strict && len(result.FailedHashes) > 0 {
+		return errIncomplete
+	}
+
+	return nil
+}
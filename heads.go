@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ofen/getblock-go/eth"
+)
+
+// Longest wait between attempts to reconnect a dropped subscription
+const maxReconnectDelay = time.Minute
+
+// Send the latest head to out until ctx is cancelled
+// With a WebSocket URL new heads are pushed by the node, otherwise or when it can't be reached we poll
+func followHeads(ctx context.Context, opts options, client *eth.Client, out chan *big.Int) {
+	if opts.wsURL != "" {
+		conn, err := subscribeHeads(ctx, opts.wsDialer, opts.wsURL)
+		if err == nil {
+			readHeads(ctx, opts.wsDialer, opts.wsURL, conn, out)
+			return
+		}
+
+		slog.Warn("cannot subscribe to new heads, polling instead", "url", opts.wsURL, "err", err)
+	}
+
+	pollHeads(ctx, client, opts.pollInterval, out)
+}
+
+// Ask for the head every interval
+func pollHeads(ctx context.Context, client *eth.Client, interval time.Duration, out chan *big.Int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// A missed poll is not fatal, the next one picks up the same blocks
+			slog.Warn("cannot get latest block number", "err", err)
+			continue
+		}
+
+		offerHead(out, head)
+	}
+}
+
+// Open a WebSocket and start an eth_subscribe newHeads subscription on it
+func subscribeHeads(ctx context.Context, dialer *websocket.Dialer, url string) (*websocket.Conn, error) {
+	conn, _, err := dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "eth_subscribe", "params": []string{"newHeads"}}
+	if err := conn.WriteJSON(request); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	var response struct {
+		Result string          `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := conn.ReadJSON(&response); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if len(response.Error) > 0 || response.Result == "" {
+		conn.Close()
+		return nil, fmt.Errorf("eth_subscribe failed: %s", response.Error)
+	}
+
+	return conn, nil
+}
+
+// Forward the heads of a subscription, reconnecting whenever the connection drops
+func readHeads(ctx context.Context, dialer *websocket.Dialer, url string, conn *websocket.Conn, out chan *big.Int) {
+	// Closing the connection is the only way to unblock a pending read
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer func() { stop(); conn.Close() }()
+
+	var notification struct {
+		Params struct {
+			Result struct {
+				Number string `json:"number"`
+			} `json:"result"`
+		} `json:"params"`
+	}
+
+	for attempt := 0; ; {
+		if err := conn.ReadJSON(&notification); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			slog.Warn("new heads subscription dropped, reconnecting", "err", err)
+
+			conn = reconnect(ctx, dialer, url, &attempt)
+			if conn == nil {
+				return
+			}
+
+			stop()
+			stop = context.AfterFunc(ctx, func() { conn.Close() })
+			continue
+		}
+
+		attempt = 0
+
+		head, ok := new(big.Int).SetString(notification.Params.Result.Number, 0)
+		if !ok {
+			slog.Debug("ignoring head without a block number", "number", notification.Params.Result.Number)
+			continue
+		}
+
+		offerHead(out, head)
+	}
+}
+
+// Keep trying to subscribe again with a growing delay, nil once ctx is cancelled
+func reconnect(ctx context.Context, dialer *websocket.Dialer, url string, attempt *int) *websocket.Conn {
+	for {
+		delay := time.Second << *attempt
+		if delay > maxReconnectDelay || delay <= 0 {
+			delay = maxReconnectDelay
+		} else {
+			*attempt++
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+
+		conn, err := subscribeHeads(ctx, dialer, url)
+		if err == nil {
+			return conn
+		}
+
+		slog.Warn("cannot resubscribe to new heads", "err", err, "retry_in", delay)
+	}
+}
+
+// Hand over the newest head without blocking
+// A head nobody picked up yet is replaced, the scan of the newer one covers its blocks too
+func offerHead(out chan *big.Int, head *big.Int) {
+	select {
+	case out <- head:
+	default:
+		select {
+		case <-out:
+		default:
+		}
+		out <- head
+	}
+}
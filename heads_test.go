@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	getblock "github.com/ofen/getblock-go"
+	"github.com/ofen/getblock-go/eth"
+)
+
+// A WebSocket node that accepts a newHeads subscription and pushes the heads of one connection, then hangs up
+// Each connection gets the next set of heads
+func newFakeSubscription(t *testing.T, connections ...[]uint64) (string, *atomic.Int32) {
+	t.Helper()
+
+	var dialed atomic.Int32
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		i := int(dialed.Add(1)) - 1
+
+		var request rpcRequest
+		if err := conn.ReadJSON(&request); err != nil || request.Method != "eth_subscribe" {
+			return
+		}
+		conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": "0x1"})
+
+		if i >= len(connections) {
+			// Stay up without sending anything until the test is over
+			conn.ReadMessage()
+			return
+		}
+		for _, head := range connections[i] {
+			conn.WriteJSON(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"method":  "eth_subscription",
+				"params":  map[string]interface{}{"subscription": "0x1", "result": map[string]string{"number": fmt.Sprintf("%#x", head)}},
+			})
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http"), &dialed
+}
+
+// Read heads until want comes along, failing on anything that goes backwards
+func waitForHead(t *testing.T, heads chan *big.Int, want int64) {
+	t.Helper()
+
+	last := big.NewInt(0)
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case head := <-heads:
+			if head.Cmp(last) < 0 {
+				t.Fatalf("head went back from %s to %s", last, head)
+			}
+			last = head
+			if head.Int64() == want {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("never got head %d, last one was %s", want, last)
+		}
+	}
+}
+
+func TestSubscriptionDeliversHeads(t *testing.T) {
+	url, _ := newFakeSubscription(t, []uint64{101, 102, 103})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	heads := make(chan *big.Int, 1)
+	go followHeads(ctx, options{wsURL: url, wsDialer: websocket.DefaultDialer}, nil, heads)
+
+	waitForHead(t, heads, 103)
+}
+
+func TestSubscriptionReconnects(t *testing.T) {
+	url, dialed := newFakeSubscription(t, []uint64{101}, []uint64{102})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	heads := make(chan *big.Int, 1)
+	go followHeads(ctx, options{wsURL: url, wsDialer: websocket.DefaultDialer}, nil, heads)
+
+	// The second head only comes over the second connection
+	waitForHead(t, heads, 102)
+	if got := dialed.Load(); got < 2 {
+		t.Errorf("dialed %d times", got)
+	}
+}
+
+func TestHeadsFallBackToPolling(t *testing.T) {
+	node, server := newFakeNode(t, 100)
+	node.top = 102
+
+	// Nothing speaks WebSocket here
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &eth.Client{Client: &getblock.Client{Client: newRPCClient(server.URL, "", nil)}}
+	opts := options{wsURL: url, wsDialer: websocket.DefaultDialer, pollInterval: 5 * time.Millisecond}
+
+	heads := make(chan *big.Int, 1)
+	go followHeads(ctx, opts, client, heads)
+
+	waitForHead(t, heads, 102)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// Widest bar of the histogram, the other bars are scaled against it
+const histogramWidth = 40
+
+// Parse -histogram-buckets, a comma separated list of ascending ETH amounts
+func parseBuckets(list string) ([]*big.Int, error) {
+	bounds := []*big.Int{}
+
+	for _, field := range strings.Split(list, ",") {
+		bound, err := parseEther(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+
+		if n := len(bounds); n > 0 && bound.Cmp(bounds[n-1]) <= 0 {
+			return nil, fmt.Errorf("bucket bounds must be ascending, got %s after %s", field, exactEther(bounds[n-1]))
+		}
+
+		bounds = append(bounds, bound)
+	}
+
+	return bounds, nil
+}
+
+// Print how many transfers fell into each value bucket, with a bar per bucket
+func renderHistogram(w io.Writer, bounds []*big.Int, counts []int, currency string) {
+	most := 0
+	for _, count := range counts {
+		if count > most {
+			most = count
+		}
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{fmt.Sprintf("Value (%s)", currency), "Transfers", ""})
+	table.SetAutoWrapText(false)
+
+	for i, count := range counts {
+		var label string
+		switch {
+		case i == 0:
+			label = "< " + exactEther(bounds[0])
+		case i == len(bounds):
+			label = exactEther(bounds[i-1]) + "+"
+		default:
+			label = exactEther(bounds[i-1]) + " - " + exactEther(bounds[i])
+		}
+
+		bar := 0
+		if most > 0 {
+			bar = (count*histogramWidth + most - 1) / most
+		}
+
+		table.Append([]string{label, strconv.Itoa(count), strings.Repeat("#", bar)})
+	}
+
+	fmt.Fprintln(w)
+	table.Render()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBuckets(t *testing.T) {
+	bounds, err := parseBuckets("0.01, 0.1,1,10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bounds) != 4 || bounds[0].String() != "10000000000000000" || bounds[3].String() != "10000000000000000000" {
+		t.Errorf("bounds %v", bounds)
+	}
+
+	for _, list := range []string{"1,0.5", "1,1", "1,lots"} {
+		if _, err := parseBuckets(list); err == nil {
+			t.Errorf("%q accepted", list)
+		}
+	}
+}
+
+func TestHistogramLabelsEveryBucket(t *testing.T) {
+	bounds, err := parseBuckets("0.1,1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	renderHistogram(&out, bounds, []int{4, 2, 0}, "ETH")
+
+	for _, want := range []string{"< 0.1", "0.1 - 1", "1+", "########################################"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("histogram is missing %q\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// A standalone page, styles and the sorting script are inline so the file can be mailed around on its own
+// Clicking a column header sorts by it, numeric columns sort by the data-value of their cells
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{"rank": func(i int) int { return i + 1 }}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Balance changes{{if .First}} for blocks {{.First}} to {{.Last}}{{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+dl { display: grid; grid-template-columns: max-content auto; gap: .2em 1.5em; }
+dt { color: #666; }
+dd { margin: 0; }
+table { border-collapse: collapse; margin-top: 1.5em; }
+th, td { padding: .3em .8em; border-bottom: 1px solid #ddd; text-align: left; }
+th { cursor: pointer; background: #f4f4f4; user-select: none; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+td.address { font-family: monospace; }
+.negative { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Balance changes</h1>
+<dl>
+{{if .First}}<dt>Blocks</dt><dd>{{.First}} to {{.Last}} ({{.Blocks}} blocks)</dd>{{end}}
+<dt>Transactions</dt><dd>{{.Stats.Transactions}}</dd>
+<dt>Value transfers</dt><dd>{{.Stats.Transfers}}</dd>
+<dt>Volume</dt><dd>{{.Volume}} {{.Currency}}</dd>
+{{if .Burned}}<dt>Burned</dt><dd>{{.Burned}} {{.Currency}}</dd>
+{{end}}<dt>Addresses</dt><dd>{{.Addresses}}</dd>
+{{if .Generated}}<dt>Duration</dt><dd>{{.Duration}}</dd>
+<dt>Generated</dt><dd>{{.Generated}}</dd>
+{{end}}</dl>
+{{if .Empty}}<p>{{.Empty}}</p>
+{{end}}<table id="results">
+<thead><tr><th>#</th><th>Address</th><th>Label</th><th>Sent ({{.Unit}})</th><th>Received ({{.Unit}})</th><th>Total Change ({{.Unit}})</th><th>Tx Count</th></tr></thead>
+<tbody>
+{{range $i, $r := .Rows}}<tr><td class="num" data-value="{{$i}}">{{$i | rank}}</td><td class="address">{{$r.Address}}</td><td>{{$r.Label}}{{if $r.Name}} {{$r.Name}}{{end}}{{if $r.Type}} ({{$r.Type}}){{end}}</td><td class="num" data-value="{{$r.SentWei}}" title="{{$r.SentWei}} wei">{{$r.Sent}}</td><td class="num" data-value="{{$r.ReceivedWei}}" title="{{$r.ReceivedWei}} wei">{{$r.Received}}</td><td class="num{{if $r.Negative}} negative{{end}}" data-value="{{$r.ChangeWei}}" title="{{$r.ChangeWei}} wei">{{$r.Change}}</td><td class="num" data-value="{{$r.TxCount}}">{{$r.TxCount}}</td></tr>
+{{end}}</tbody>
+</table>
+<script>
+document.querySelectorAll("#results th").forEach(function (th, column) {
+  var ascending = false;
+  th.addEventListener("click", function () {
+    var body = document.querySelector("#results tbody");
+    var rows = Array.from(body.rows);
+    ascending = !ascending;
+    rows.sort(function (a, b) {
+      var x = a.cells[column], y = b.cells[column], order;
+      if (x.dataset.value !== undefined) {
+        var d = BigInt(x.dataset.value) - BigInt(y.dataset.value);
+        order = d > 0n ? 1 : d < 0n ? -1 : 0;
+      } else {
+        order = x.textContent.localeCompare(y.textContent);
+      }
+      return ascending ? order : -order;
+    });
+    rows.forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+type htmlRow struct {
+	Address, Label, Name, Type      string
+	Sent, Received, Change          string
+	SentWei, ReceivedWei, ChangeWei string
+	Negative                        bool
+	TxCount                         int
+}
+
+// Write the results as a self contained HTML page, html/template escapes every value that goes in
+// Without metadata the page leaves out the duration and generation time, so the same scan renders the same page
+// Amount cells show the exact wei in a tooltip, the displayed ether is rounded
+func renderHTML(w io.Writer, rows []report.Row, u report.Unit, result *parser.Result, took time.Duration, currency string, decimals int, metadata bool) error {
+	page := struct {
+		First, Last *big.Int
+		Blocks      int
+		Stats       parser.Stats
+		Volume      string
+		Burned      string
+		Currency    string
+		Addresses   int
+		Duration    time.Duration
+		Generated   string
+		Unit        string
+		Empty       string
+		Rows        []htmlRow
+	}{
+		Blocks:    result.Blocks,
+		Stats:     result.Stats,
+		Volume:    formatEther(result.Stats.Volume, decimals),
+		Currency:  currency,
+		Addresses: result.Addresses,
+		Unit:      u.Label,
+	}
+
+	if metadata {
+		page.Duration, page.Generated = took.Round(time.Millisecond), time.Now().UTC().Format(time.RFC3339)
+	}
+
+	if burned := result.Stats.Burned; burned != nil && burned.Sign() > 0 {
+		page.Burned = formatEther(burned, decimals)
+	}
+
+	if n := len(result.Headers); n > 0 {
+		page.First, page.Last = result.Headers[0].Number, result.Headers[n-1].Number
+	}
+
+	if len(rows) == 0 {
+		page.Empty = noChanges(result)
+	}
+
+	for _, r := range rows {
+		page.Rows = append(page.Rows, htmlRow{
+			Address:     r.Address,
+			Label:       r.Label,
+			Name:        r.Name,
+			Type:        r.Kind,
+			Sent:        u.Format(r.Sent),
+			Received:    u.Format(r.Received),
+			Change:      u.Format(r.Change),
+			SentWei:     r.Sent.String(),
+			ReceivedWei: r.Received.String(),
+			ChangeWei:   r.Change.String(),
+			Negative:    r.Change.Sign() < 0,
+			TxCount:     r.TxCount,
+		})
+	}
+
+	return htmlReport.Execute(w, page)
+}
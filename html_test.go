@@ -0,0 +1,105 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// The HTML report of the golden rows with these labels, or of no rows at all without any
+func htmlPage(t *testing.T, labels ...string) string {
+	t.Helper()
+
+	u, err := lookupUnit("eth", "ETH", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := goldenRows(t)
+	for i, label := range labels {
+		rows[i].Label = label
+	}
+	if labels == nil {
+		rows = nil
+	}
+
+	result := &parser.Result{
+		Blocks:  11,
+		Stats:   parser.Stats{Transactions: 20, Transfers: 16, Volume: wei(t, "24691357802469135781"), Burned: new(big.Int)},
+		Headers: []parser.Header{{Number: big.NewInt(100)}, {Number: big.NewInt(110)}},
+	}
+
+	var out strings.Builder
+	if err := renderHTML(&out, rows, u, result, time.Second, "ETH", 4, false); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestHTMLReportHasARowPerAddress(t *testing.T) {
+	page := htmlPage(t, "vitalik", "", "binance")
+
+	if got := strings.Count(page, "<tr><td"); got != 3 {
+		t.Errorf("page has %d result rows, want 3", got)
+	}
+	for _, want := range []string{
+		`<td class="address">0xd8da6bf26964af9d7eed9e03e53415d37aa96045</td><td>vitalik</td>`,
+		`data-value="-12345678901234567891" title="-12345678901234567891 wei">-12.3457</td>`,
+		`class="num negative"`,
+		"<dd>16</dd>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+}
+
+func TestHTMLReportEscapesLabels(t *testing.T) {
+	page := htmlPage(t, `<script>alert("hi")</script>`)
+
+	if strings.Contains(page, `<script>alert`) {
+		t.Error("a label made it into the page as markup")
+	}
+	if !strings.Contains(page, "&lt;script&gt;alert(&#34;hi&#34;)&lt;/script&gt;") {
+		t.Error("the escaped label is missing")
+	}
+}
+
+func TestHTMLReportWithoutRows(t *testing.T) {
+	page := htmlPage(t)
+
+	if strings.Contains(page, "<tr><td") || !strings.Contains(page, "No balance changes found") {
+		t.Error("empty page doesn't say there was nothing to show")
+	}
+}
+
+// The sort script of the page, a broken one leaves every column unsortable without any other sign
+func TestHTMLSortScript(t *testing.T) {
+	page := htmlPage(t, "Alice")
+
+	start, end := strings.Index(page, "<script>"), strings.Index(page, "</script>")
+	if start < 0 || end < start {
+		t.Fatalf("no script in\n%s", page)
+	}
+	script := page[start+len("<script>") : end]
+	golden(t, "html_script", []byte(script))
+
+	// Where node is around, check that the script parses as well
+	node, err := exec.LookPath("node")
+	if err != nil {
+		return
+	}
+	path := filepath.Join(t.TempDir(), "sort.js")
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(node, "--check", path).CombinedOutput(); err != nil {
+		t.Errorf("script does not parse: %v\n%s", err, out)
+	}
+}
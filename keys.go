@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// How long a key that was rejected or rate limited is left out of the rotation
+const keyCooldown = 30 * time.Second
+
+// Spreads requests over several GetBlock API keys in turn, so a scan is not held to the rate limit of one key
+// It sits under getblock.Client, so retries, batches and everything else above it work unchanged
+// A key whose request is rejected or rate limited is skipped for keyCooldown
+type keyRing struct {
+	keys []*apiKey
+	next atomic.Uint64
+}
+
+// One key of the ring with its own JSON-RPC client
+type apiKey struct {
+	client jsonrpc.RPCClient
+
+	// A short form safe for logs, never the key itself
+	name string
+
+	mu        sync.Mutex
+	calls     int
+	errors    int
+	skipUntil time.Time
+}
+
+func newKeyRing(keys []string, endpoint string, httpClient *http.Client) *keyRing {
+	ring := &keyRing{}
+	for _, key := range keys {
+		ring.keys = append(ring.keys, &apiKey{client: newRPCClient(endpoint, key, httpClient), name: keyName(key)})
+	}
+
+	return ring
+}
+
+// The last four characters, enough to tell keys apart in the logs
+func keyName(key string) string {
+	if len(key) <= 8 {
+		return "****"
+	}
+	return "..." + key[len(key)-4:]
+}
+
+// The next key in turn that is not being skipped
+// When every key is skipped the one that comes back first is used, requests are never held up here
+func (r *keyRing) pick() *apiKey {
+	start := int(r.next.Add(1) - 1)
+	now := time.Now()
+
+	var soonest *apiKey
+	var soonestUntil time.Time
+	for i := range r.keys {
+		key := r.keys[(start+i)%len(r.keys)]
+
+		key.mu.Lock()
+		until := key.skipUntil
+		key.mu.Unlock()
+
+		if !now.Before(until) {
+			return key
+		}
+		if soonest == nil || until.Before(soonestUntil) {
+			soonest, soonestUntil = key, until
+		}
+	}
+
+	return soonest
+}
+
+// Ask every key for the latest block, so a revoked or mistyped key is reported before the scan rather than in the middle of it
+func (r *keyRing) check(ctx context.Context) error {
+	for _, key := range r.keys {
+		if _, err := latestBlock(ctx, key.client, true); err != nil {
+			return fmt.Errorf("API key %s: %w", key.name, err)
+		}
+	}
+
+	return nil
+}
+
+// Count the outcome of a request, and take the key out of the rotation for a while if it was refused
+func (k *apiKey) record(err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.calls++
+	if err == nil {
+		return
+	}
+	k.errors++
+
+	if refusedKey(err) && !time.Now().Before(k.skipUntil) {
+		k.skipUntil = time.Now().Add(keyCooldown)
+		slog.Warn("API key refused a request, skipping it for a while", "key", k.name, "cooldown", keyCooldown, "errors", k.errors, "calls", k.calls, "err", err)
+	}
+}
+
+// Whether err says the key itself is the problem, it is unauthorized or used up its rate limit
+func refusedKey(err error) bool {
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) && (httpErr.Code == http.StatusUnauthorized || httpErr.Code == http.StatusForbidden) {
+		return true
+	}
+
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) && isAuthMessage(rpcErr.Message) {
+		return true
+	}
+
+	return parser.IsRateLimited(err)
+}
+
+// The error of a single answer, whether it failed over HTTP or came back as a JSON-RPC error
+func responseError(r *jsonrpc.RPCResponse, err error) error {
+	if err == nil && r != nil && r.Error != nil {
+		return r.Error
+	}
+	return err
+}
+
+// Log how each key fared, a key with a high error rate is worth a look
+func (r *keyRing) logUsage() {
+	for _, key := range r.keys {
+		key.mu.Lock()
+		rate := 0.0
+		if key.calls > 0 {
+			rate = float64(key.errors) / float64(key.calls)
+		}
+		slog.Info("API key usage", "key", key.name, "calls", key.calls, "errors", key.errors, "error_rate", rate)
+		key.mu.Unlock()
+	}
+}
+
+func (r *keyRing) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	key := r.pick()
+	resp, err := key.client.Call(ctx, method, params...)
+	key.record(responseError(resp, err))
+	return resp, err
+}
+
+func (r *keyRing) CallRaw(ctx context.Context, request *jsonrpc.RPCRequest) (*jsonrpc.RPCResponse, error) {
+	key := r.pick()
+	resp, err := key.client.CallRaw(ctx, request)
+	key.record(responseError(resp, err))
+	return resp, err
+}
+
+func (r *keyRing) CallFor(ctx context.Context, out interface{}, method string, params ...interface{}) error {
+	key := r.pick()
+	err := key.client.CallFor(ctx, out, method, params...)
+	key.record(err)
+	return err
+}
+
+// A batch goes out on one key, the first error among its answers counts for that key
+func (r *keyRing) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	key := r.pick()
+	responses, err := key.client.CallBatch(ctx, requests)
+	key.record(batchError(responses, err))
+	return responses, err
+}
+
+func (r *keyRing) CallBatchRaw(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	key := r.pick()
+	responses, err := key.client.CallBatchRaw(ctx, requests)
+	key.record(batchError(responses, err))
+	return responses, err
+}
+
+func batchError(responses jsonrpc.RPCResponses, err error) error {
+	if err != nil {
+		return err
+	}
+	for _, r := range responses {
+		if r != nil && r.Error != nil {
+			return r.Error
+		}
+	}
+	return nil
+}
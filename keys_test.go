@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+func TestKeyRingTakesKeysInTurn(t *testing.T) {
+	node, server := newFakeNode(t, 100)
+	ring := newKeyRing([]string{"key-one", "key-two", "key-three"}, server.URL, nil)
+
+	for i := 0; i < 9; i++ {
+		if _, err := ring.Call(context.Background(), "eth_blockNumber"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	counts := map[string]int{}
+	for _, key := range node.keys {
+		counts[key]++
+	}
+	for _, key := range []string{"key-one", "key-two", "key-three"} {
+		if counts[key] != 3 {
+			t.Errorf("%s sent %d of 9 requests, want 3 %v", key, counts[key], counts)
+		}
+	}
+}
+
+func TestKeyRingSkipsRefusedKeys(t *testing.T) {
+	for name, status := range map[string]int{"unauthorized": http.StatusUnauthorized, "rate limited": http.StatusTooManyRequests} {
+		t.Run(name, func(t *testing.T) {
+			node, _ := newFakeNode(t, 100)
+			refusing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get(apiKeyHeader) == "key-bad" {
+					http.Error(w, "refused", status)
+					return
+				}
+				node.ServeHTTP(w, r)
+			})
+			server := httptest.NewServer(refusing)
+			t.Cleanup(server.Close)
+			ring := newKeyRing([]string{"key-bad", "key-good"}, server.URL, nil)
+
+			// The bad key is tried once, then the good one takes every request
+			failed := 0
+			for i := 0; i < 6; i++ {
+				if _, err := ring.Call(context.Background(), "eth_blockNumber"); err != nil {
+					failed++
+				}
+			}
+			if failed != 1 {
+				t.Errorf("%d requests failed, want only the first on the bad key", failed)
+			}
+
+			bad := ring.keys[0]
+			bad.mu.Lock()
+			defer bad.mu.Unlock()
+			if bad.calls != 1 || bad.errors != 1 {
+				t.Errorf("bad key made %d calls with %d errors, want 1 and 1", bad.calls, bad.errors)
+			}
+		})
+	}
+}
+
+func TestKeyRingUsesASkippedKeyWhenAllAre(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+	ring := newKeyRing([]string{"key-one", "key-two"}, server.URL, nil)
+	for _, key := range ring.keys {
+		key.record(&jsonrpc.HTTPError{Code: http.StatusUnauthorized})
+	}
+
+	// Requests are never held up, the key that comes back first gets them
+	if key := ring.pick(); key != ring.keys[0] {
+		t.Errorf("picked %s, want the first key to come back", key.name)
+	}
+}
+
+func TestKeyNameHidesTheKey(t *testing.T) {
+	for key, want := range map[string]string{"abcdefghijkl": "...ijkl", "short": "****", "": "****"} {
+		if got := keyName(key); got != want {
+			t.Errorf("%q shown as %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEveryKeyIsCheckedBeforeTheScan(t *testing.T) {
+	node, _ := newFakeNode(t, 100)
+	refusing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(apiKeyHeader) == "revoked-key-2222" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		node.ServeHTTP(w, r)
+	})
+	server := httptest.NewServer(refusing)
+	t.Cleanup(server.Close)
+
+	// The revoked key is not the first, so the first eth_blockNumber alone would not find it
+	opts := testOptions(t, server.URL)
+	opts.apiKeys = []string{"working-key-1111", "revoked-key-2222", "working-key-3333"}
+
+	err := runParser(context.Background(), opts, parser.Config{Workers: 2})
+	if err == nil || !strings.Contains(err.Error(), "API key ...2222") || !strings.Contains(err.Error(), "invalid or unauthorized API key") {
+		t.Fatalf("got %v, want the revoked key named", err)
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if len(node.blocks) != 0 {
+		t.Errorf("fetched blocks %v with a revoked key in the ring", node.blocks)
+	}
+}
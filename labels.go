@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Well known mainnet addresses, keyed lowercase like the balances
+// A -labels file adds to these and wins where both name the same address
+var builtinLabels = map[string]string{
+	"0x00000000219ab540356cbb839cbe05303d7705fa": "Beacon Deposit Contract",
+	"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2": "WETH",
+	"0x7a250d5630b4cf539739df2c5dacb4c659f2488d": "Uniswap V2 Router",
+	"0xe592427a0aece92de3edee1f18e0157c05861564": "Uniswap V3 Router",
+	"0x3f5ce5fbfe3e9af3971dd833d26ba9b5c936f0be": "Binance Hot Wallet",
+	"0x28c6c06298d514db089934071355e5743bf21d60": "Binance Hot Wallet 14",
+	"0x71660c4005ba85c37ccec55d0c4493e66fe775d3": "Coinbase",
+	"0xa9d1e08c7793af67e9d92fe308d5697fb81d3e43": "Coinbase 10",
+}
+
+// Merge the built in labels with an optional JSON file mapping address to name
+func loadLabels(path string) (map[string]string, error) {
+	labels := make(map[string]string, len(builtinLabels))
+	for address, name := range builtinLabels {
+		labels[address] = name
+	}
+
+	if path == "" {
+		return labels, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	extra := map[string]string{}
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return nil, fmt.Errorf("labels file %s: %w", path, err)
+	}
+
+	for address, name := range extra {
+		if !addressPattern.MatchString(address) {
+			return nil, fmt.Errorf("labels file %s: invalid address %q", path, address)
+		}
+		labels[parser.NormalizeAddress(address)] = name
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLabelsFileAddsToTheBuiltInList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.json")
+	data := `{"0x00000000000000000000000000000000000000AA": "Treasury", "0x28c6c06298d514db089934071355e5743bf21d60": "Binance 14"}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	labels, err := loadLabels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys are lowercase whatever case the file used, and the file wins over the built in name
+	for address, want := range map[string]string{
+		"0x00000000000000000000000000000000000000aa": "Treasury",
+		"0x28c6c06298d514db089934071355e5743bf21d60": "Binance 14",
+		"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2": "WETH",
+	} {
+		if got := labels[address]; got != want {
+			t.Errorf("%s is labeled %q, want %q", address, got, want)
+		}
+	}
+}
+
+func TestLabelsFileRejectsBadAddresses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.json")
+	if err := os.WriteFile(path, []byte(`{"vitalik.eth": "Vitalik"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadLabels(path); err == nil {
+		t.Error("a name was accepted as an address")
+	}
+}
+
+func TestLabeledRowsShowTheirName(t *testing.T) {
+	labels, err := loadLabels("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := options{labels: labels, checksum: true}
+	balances := mixedBalances(2)
+	balances["0x3f5ce5fbfe3e9af3971dd833d26ba9b5c936f0be"] = wei(t, "1000")
+
+	for _, row := range renderedRows(t, opts, balances) {
+		want := ""
+		if row.Address == "0x3f5CE5FBFe3E9af3971dD833D26bA9b5C936f0bE" {
+			want = "Binance Hot Wallet"
+		}
+		if row.Label != want {
+			t.Errorf("%s is labeled %q, want %q", row.Address, row.Label, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// The per address lookups that fill extra columns of the displayed rows, each one nil unless its flag is set
+// They cache their answers, so one set is shared between reports and watch mode never looks an address up twice
+type lookups struct {
+	names *parser.Resolver
+	types *parser.Classifier
+}
+
+// The lookups opts asks for, nil when there are none
+func newLookups(client parser.Client, config parser.Config, opts options) *lookups {
+	if !opts.ens && !opts.classify {
+		return nil
+	}
+
+	l := &lookups{}
+	if opts.ens {
+		l.names = parser.NewResolver(client, config)
+	}
+	if opts.classify {
+		l.types = parser.NewClassifier(client, config)
+	}
+
+	return l
+}
+
+// Fill in the ENS name and address type of every row, a nil set leaves the rows alone
+func (l *lookups) annotate(ctx context.Context, rows []report.Row) {
+	if l == nil {
+		return
+	}
+
+	for i := range rows {
+		if l.names != nil {
+			rows[i].Name = l.names.Name(ctx, rows[i].Address)
+		}
+		if l.types != nil {
+			rows[i].Kind = l.types.Type(ctx, rows[i].Address)
+		}
+	}
+}
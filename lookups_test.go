@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestClassifyTypesOnlyTheDisplayedRows(t *testing.T) {
+	node, server := newFakeNode(t, 100)
+
+	opts := testOptions(t, server.URL)
+	opts.classify = true
+	opts.top = 1
+
+	rows := runJSON(t, opts)
+	if len(rows) != 1 || rows[0].Address != bob || rows[0].Type != "Contract" {
+		t.Fatalf("got %+v, want bob as a contract", rows)
+	}
+
+	// Alice is left out of the report, so her code is never asked for
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	codes := 0
+	for _, method := range node.methods {
+		if method == "eth_getCode" {
+			codes++
+		}
+	}
+	if codes != 1 {
+		t.Errorf("%d eth_getCode calls, want 1", codes)
+	}
+}
+
+func TestClassifyTellsWalletsApart(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	opts := testOptions(t, server.URL)
+	opts.classify = true
+
+	for _, row := range runJSON(t, opts) {
+		want := map[string]string{alice: "EOA", bob: "Contract"}[row.Address]
+		if row.Type != want {
+			t.Errorf("%s is %q, want %q", row.Address, row.Type, want)
+		}
+	}
+	if rows := runJSON(t, testOptions(t, server.URL)); rows[0].Type != "" {
+		t.Errorf("typed %+v without -classify", rows[0])
+	}
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+const (
+	alice = "0x000000000000000000000000000000000000000a"
+	bob   = "0x000000000000000000000000000000000000000b"
+	carol = "0x000000000000000000000000000000000000000c"
+	dave  = "0x000000000000000000000000000000000000000d"
+)
+
+// A JSON-RPC node over HTTP where block n holds one transfer of n wei from alice to bob
+// It answers single requests and batches, and remembers every method it was asked for
+type fakeNode struct {
+	head uint64
+
+	// When set the head moves up a block with every eth_blockNumber after the first, until it gets here
+	top uint64
+
+	// Every block is empty, not a single transaction
+	empty bool
+
+	// Blocks replaced by a reorg, version v of block n sends n + 1000v wei and has its own hash
+	versions map[uint64]uint64
+
+	// Blocks whose first version also sends 5 wei from carol to dave, addresses no other block has
+	extra map[uint64]bool
+
+	mu      sync.Mutex
+	asked   bool
+	methods []string
+	blocks  []uint64
+	keys    []string
+}
+
+func newFakeNode(t *testing.T, head uint64) (*fakeNode, *httptest.Server) {
+	t.Helper()
+
+	node := &fakeNode{head: head}
+	server := httptest.NewServer(node)
+	t.Cleanup(server.Close)
+
+	return node, server
+}
+
+type rpcRequest struct {
+	ID     int               `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+func (n *fakeNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n.mu.Lock()
+	n.keys = append(n.keys, r.Header.Get(apiKeyHeader))
+	n.mu.Unlock()
+
+	if len(body) > 0 && body[0] == '[' {
+		var requests []rpcRequest
+		if err := json.Unmarshal(body, &requests); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		answers := []map[string]interface{}{}
+		for _, request := range requests {
+			answers = append(answers, n.answer(request))
+		}
+		json.NewEncoder(w).Encode(answers)
+		return
+	}
+
+	var request rpcRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(n.answer(request))
+}
+
+func (n *fakeNode) answer(request rpcRequest) map[string]interface{} {
+	n.mu.Lock()
+	n.methods = append(n.methods, request.Method)
+	n.mu.Unlock()
+
+	answer := map[string]interface{}{"jsonrpc": "2.0", "id": request.ID}
+
+	switch request.Method {
+	case "eth_blockNumber":
+		n.mu.Lock()
+		if n.asked && n.head < n.top {
+			n.head++
+		}
+		n.asked = true
+		answer["result"] = fmt.Sprintf("%#x", n.head)
+		n.mu.Unlock()
+	case "eth_getBlockByNumber":
+		var tag string
+		json.Unmarshal(request.Params[0], &tag)
+
+		// The pending block is the one after the head
+		if tag == "pending" {
+			n.mu.Lock()
+			tag = fmt.Sprintf("%#x", n.head+1)
+			n.mu.Unlock()
+		}
+		number, err := strconv.ParseUint(tag, 0, 64)
+		if err != nil {
+			answer["error"] = map[string]interface{}{"code": -32602, "message": "bad block " + tag}
+			break
+		}
+
+		n.mu.Lock()
+		n.blocks = append(n.blocks, number)
+		block := fakeBlock(number, n.versions[number], n.versions[number-1])
+		if n.empty {
+			block["transactions"] = []interface{}{}
+		}
+		if n.extra[number] && n.versions[number] == 0 {
+			block["transactions"] = append(block["transactions"].([]interface{}), map[string]interface{}{
+				"hash":  fmt.Sprintf("0x%063x2", number),
+				"from":  carol,
+				"to":    dave,
+				"value": "0x5",
+			})
+		}
+		answer["result"] = block
+		n.mu.Unlock()
+	case "eth_getTransactionReceipt":
+		var hash string
+		json.Unmarshal(request.Params[0], &hash)
+		answer["result"] = map[string]interface{}{"transactionHash": hash, "gasUsed": "0x0", "effectiveGasPrice": "0x0", "logs": []interface{}{}}
+	case "eth_getCode":
+		// Bob is a contract, everyone else a wallet
+		var address string
+		json.Unmarshal(request.Params[0], &address)
+		answer["result"] = "0x"
+		if address == bob {
+			answer["result"] = "0x6080604052"
+		}
+	default:
+		answer["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+	}
+
+	return answer
+}
+
+func fakeBlock(number, version, parentVersion uint64) map[string]interface{} {
+	return map[string]interface{}{
+		"number":     fmt.Sprintf("%#x", number),
+		"hash":       fmt.Sprintf("0x%032x%032x", version, number),
+		"parentHash": fmt.Sprintf("0x%032x%032x", parentVersion, number-1),
+		"transactions": []interface{}{map[string]interface{}{
+			"hash":  fmt.Sprintf("0x%031x%032x1", version, number),
+			"from":  alice,
+			"to":    bob,
+			"value": fmt.Sprintf("%#x", number+1000*version),
+		}},
+	}
+}
+
+// The blocks the node was asked for, in the order the requests came in
+func (n *fakeNode) fetched() []uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]uint64(nil), n.blocks...)
+}
+
+// Options for a plain run against endpoint, writing JSON to a file in a temp directory
+func testOptions(t *testing.T, endpoint string) options {
+	t.Helper()
+
+	u, err := lookupUnit("eth", "ETH", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return options{
+		from:       -1,
+		to:         -1,
+		blocks:     10,
+		format:     "json",
+		out:        filepath.Join(t.TempDir(), "report.json"),
+		endpoint:   endpoint,
+		currency:   "ETH",
+		unit:       u,
+		decimals:   4,
+		sortKey:    "net",
+		descending: true,
+		noMetadata: true,
+	}
+}
+
+// Run a scan the way main does and decode the JSON report it wrote
+func runJSON(t *testing.T, opts options) []jsonResult {
+	t.Helper()
+
+	if err := runParser(context.Background(), opts, parser.Config{Workers: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(opts.out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []jsonResult
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatalf("%v in %s", err, data)
+	}
+	return rows
+}
+
+// The net change of bob in the report, the sum of the scanned block numbers
+func received(t *testing.T, rows []jsonResult) *big.Int {
+	t.Helper()
+
+	for _, row := range rows {
+		if row.Address == bob {
+			change, ok := new(big.Int).SetString(row.ChangeWei, 10)
+			if !ok {
+				t.Fatalf("bad change %q", row.ChangeWei)
+			}
+			return change
+		}
+	}
+	t.Fatalf("no row for bob in %v", rows)
+	return nil
+}
+
+func TestRunScansTheLatestBlocks(t *testing.T) {
+	node, server := newFakeNode(t, 100)
+
+	opts := testOptions(t, server.URL)
+	opts.blocks = 3
+
+	// Blocks 98, 99 and 100
+	if got := received(t, runJSON(t, opts)); got.Int64() != 297 {
+		t.Errorf("bob received %s, want 297", got)
+	}
+	if got := node.fetched(); len(got) != 3 {
+		t.Errorf("fetched %v", got)
+	}
+}
+
+func TestRunScansAnExplicitRange(t *testing.T) {
+	node, server := newFakeNode(t, 100)
+
+	opts := testOptions(t, server.URL)
+	opts.from, opts.to = 3, 5
+
+	if got := received(t, runJSON(t, opts)); got.Int64() != 12 {
+		t.Errorf("bob received %s, want 12", got)
+	}
+	for _, block := range node.fetched() {
+		if block < 3 || block > 5 {
+			t.Errorf("fetched block %d outside of 3-5", block)
+		}
+	}
+}
+
+func TestRunRejectsARangePastTheHead(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	opts := testOptions(t, server.URL)
+	opts.from, opts.to = 90, 101
+
+	if err := runParser(context.Background(), opts, parser.Config{Workers: 2}); err == nil {
+		t.Fatal("scanned blocks the node doesn't have yet")
+	}
+}
+
+// Run main in a child process with args, for the checks that end in os.Exit
+func TestMainProcess(t *testing.T) {
+	if os.Getenv("GETBLOCKTZ_TEST_MAIN") != "1" {
+		t.Skip("only runs as the child of TestExitCodes")
+	}
+
+	var args []string
+	json.Unmarshal([]byte(os.Getenv("GETBLOCKTZ_TEST_ARGS")), &args)
+	os.Args = append([]string{"getblocktz"}, args...)
+	main()
+	os.Exit(0)
+}
+
+// Run main in a child process, returning what it wrote to stdout and stderr and its exit code
+func runMain(t *testing.T, args ...string) (string, string, int) {
+	t.Helper()
+
+	encoded, _ := json.Marshal(args)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestMainProcess$")
+	cmd.Env = append(os.Environ(), "GETBLOCKTZ_TEST_MAIN=1", "GETBLOCKTZ_TEST_ARGS="+string(encoded), "GETBLOCK_API_KEY=", "GETBLOCK_API_KEYS=")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+
+	code := 0
+	var exit *exec.ExitError
+	if errors.As(err, &exit) {
+		code = exit.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return stdout.String(), stderr.String(), code
+}
+
+func TestExitCodes(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	for name, tc := range map[string]struct {
+		args []string
+		code int
+	}{
+		"no key":          {args: []string{"-blocks", "1"}, code: 2},
+		"key and rpc url": {args: []string{"-rpc-url", server.URL, "-api-keys", "secret"}, code: 2},
+		"backwards range": {args: []string{"-rpc-url", server.URL, "-from", "5", "-to", "3"}, code: 2},
+		"half a range":    {args: []string{"-rpc-url", server.URL, "-from", "5"}, code: 2},
+		"bad workers":     {args: []string{"-rpc-url", server.URL, "-workers", "0"}, code: 2},
+		"past the head":   {args: []string{"-rpc-url", server.URL, "-from", "90", "-to", "200"}, code: 1},
+		"fine":            {args: []string{"-rpc-url", server.URL, "-blocks", "2", "-format", "json", "-quiet"}, code: 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			stdout, stderr, code := runMain(t, tc.args...)
+			if code != tc.code {
+				t.Errorf("exit code %d, want %d\n%s%s", code, tc.code, stdout, stderr)
+			}
+		})
+	}
+}
+
+// With -quiet stdout is the report and nothing else, so it can be piped straight into another tool
+func TestQuietLeavesOnlyTheReportOnStdout(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	stdout, stderr, code := runMain(t, "-rpc-url", server.URL, "-blocks", "3", "-format", "json", "-summary", "-quiet")
+	if code != 0 {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+
+	var rows []jsonResult
+	if err := json.Unmarshal([]byte(stdout), &rows); err != nil {
+		t.Fatalf("stdout is not just the report: %v\n%s", err, stdout)
+	}
+	if len(rows) != 2 {
+		t.Errorf("report has %d rows, want alice and bob", len(rows))
+	}
+
+	// Info level diagnostics are gone, not merely moved
+	if strings.Contains(stderr, "INFO") {
+		t.Errorf("info logged under -quiet\n%s", stderr)
+	}
+}
+
+func TestIncludePendingAddsTentativeTransactions(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	// Blocks 98 to 100 and the pending block 101
+	stdout, stderr, code := runMain(t, "-rpc-url", server.URL, "-blocks", "3", "-unit", "wei", "-include-pending")
+	if code != 0 {
+		t.Fatalf("exit code %d\n%s", code, stderr)
+	}
+
+	if !strings.Contains(stdout, "TENTATIVE RESULTS: 1 transactions from the pending block") {
+		t.Errorf("results not labeled as tentative\n%s", stdout)
+	}
+	if !strings.Contains(stdout, " 398 |") {
+		t.Errorf("bob's 98 + 99 + 100 + 101 wei missing\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "results include tentative transactions") {
+		t.Errorf("no warning logged\n%s", stderr)
+	}
+}
+
+// The same range gives byte for byte the same report and summary every time, in every format
+func TestNoMetadataOutputIsReproducible(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	for _, format := range []string{"table", "json", "ndjson", "csv", "markdown", "html"} {
+		t.Run(format, func(t *testing.T) {
+			args := []string{"-rpc-url", server.URL, "-from", "90", "-to", "99", "-rps", "0", "-format", format, "-summary", "-gas-stats", "-no-metadata"}
+
+			first, stderr, code := runMain(t, args...)
+			if code != 0 {
+				t.Fatalf("exit code %d\n%s", code, stderr)
+			}
+			second, _, _ := runMain(t, args...)
+			if first != second {
+				t.Errorf("two runs differ\n%s\n---\n%s", first, second)
+			}
+
+			for _, metadata := range []string{"Duration:", "RPC calls:", "generated"} {
+				if strings.Contains(first, metadata) {
+					t.Errorf("%q left in\n%s", metadata, first)
+				}
+			}
+		})
+	}
+
+	// Without the switch the table summary says how long the scan took
+	stdout, _, _ := runMain(t, "-rpc-url", server.URL, "-from", "90", "-to", "99", "-rps", "0", "-summary")
+	if !strings.Contains(stdout, "Duration:") {
+		t.Errorf("no duration without -no-metadata\n%s", stdout)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+func TestMetricsCountTheScan(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	// startMetrics does not say which port it got, so find a free one first
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	metrics, stop, err := startMetrics(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	opts := testOptions(t, server.URL)
+	opts.from, opts.to = 1, 5
+	if err := runParser(context.Background(), opts, parser.Config{Workers: 2, Metrics: metrics}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get("http://" + addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"blockparser_blocks_processed_total 5\n",
+		"blockparser_blocks_failed_total 0\n",
+		"blockparser_rpc_retries_total 0\n",
+		"blockparser_rpc_duration_seconds_count ",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics are missing %q\n%s", want, body)
+		}
+	}
+}
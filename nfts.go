@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Print the ERC-721 transfers of a token scan, one table per collection and one per holder
+// Busiest collections come first, holders are grouped by collection with the most active first
+func renderNFTs(w io.Writer, nfts map[string]int, holders map[parser.TokenHolder]*parser.NFTFlow, info map[string]parser.TokenInfo, checksum bool) {
+	display := func(address string) string {
+		if checksum {
+			return parser.ChecksumAddress(address)
+		}
+		return address
+	}
+
+	label := func(collection string) string {
+		if symbol := info[collection].Symbol; symbol != "" {
+			return symbol + " (" + display(collection) + ")"
+		}
+		return display(collection)
+	}
+
+	collections := make([]string, 0, len(nfts))
+	for collection := range nfts {
+		collections = append(collections, collection)
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		if nfts[collections[i]] != nfts[collections[j]] {
+			return nfts[collections[i]] > nfts[collections[j]]
+		}
+		return collections[i] < collections[j]
+	})
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "NFT transfers")
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"#", "Collection", "Transfers"})
+	table.SetAutoWrapText(false)
+	for i, collection := range collections {
+		table.Append([]string{strconv.Itoa(i + 1), label(collection), strconv.Itoa(nfts[collection])})
+	}
+	table.Render()
+
+	// Collections keep the order of the table above
+	rank := make(map[string]int, len(collections))
+	for i, collection := range collections {
+		rank[collection] = i
+	}
+
+	keys := make([]parser.TokenHolder, 0, len(holders))
+	for key := range holders {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if rank[keys[i].Token] != rank[keys[j].Token] {
+			return rank[keys[i].Token] < rank[keys[j].Token]
+		}
+		one, two := holders[keys[i]], holders[keys[j]]
+		if one.Sent+one.Received != two.Sent+two.Received {
+			return one.Sent+one.Received > two.Sent+two.Received
+		}
+		return keys[i].Holder < keys[j].Holder
+	})
+
+	holderTable := tablewriter.NewWriter(w)
+	holderTable.SetHeader([]string{"#", "Collection", "Holder", "Received", "Sent"})
+	holderTable.SetAutoWrapText(false)
+	for i, key := range keys {
+		flow := holders[key]
+		holderTable.Append([]string{strconv.Itoa(i + 1), label(key.Token), display(key.Holder), strconv.Itoa(flow.Received), strconv.Itoa(flow.Sent)})
+	}
+
+	fmt.Fprintln(w)
+	holderTable.Render()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Open where a report goes, stdout unless -out names a file
+// A file is written under a temp name next to it and only renamed into place by finish, so a crash never leaves half a report
+// finish takes the error of the writing, on failure the temp file is dropped and the old report stays
+func openOutput(path string) (io.Writer, func(err error) error, error) {
+	if path == "" {
+		return os.Stdout, func(err error) error { return err }, nil
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	finish := func(err error) error {
+		if err == nil {
+			// Temp files are private, the report should be readable like any other file
+			err = file.Chmod(0o644)
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(file.Name(), path)
+		}
+		if err != nil {
+			os.Remove(file.Name())
+		}
+
+		return err
+	}
+
+	return file, finish, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONReportWrittenToANewDirectory(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	opts := testOptions(t, server.URL)
+	dir := filepath.Join(t.TempDir(), "reports", "daily")
+	opts.out = filepath.Join(dir, "report.json")
+	opts.from, opts.to = 1, 3
+
+	if got := received(t, runJSON(t, opts)); got.Int64() != 6 {
+		t.Errorf("bob received %s, want 6", got)
+	}
+
+	// Nothing but the report, the temp file was renamed into place
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "report.json" {
+		t.Errorf("directory holds %v", entries)
+	}
+	if info, err := os.Stat(opts.out); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("report mode %v, %v", info.Mode(), err)
+	}
+}
+
+func TestFailedWriteKeepsTheOldReport(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.json")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, finish, err := openOutput(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(out, "half a rep")
+
+	failure := errors.New("scan failed")
+	if err := finish(failure); !errors.Is(err, failure) {
+		t.Errorf("finish returned %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "old\n" {
+		t.Errorf("report is now %q, %v", data, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temp file left behind: %v", entries)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// Split rows into pages of size rows, the last page holds whatever is left
+func paginate(rows []report.Row, size int) [][]report.Row {
+	pages := [][]report.Row{}
+
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+		pages = append(pages, rows[start:end])
+	}
+
+	return pages
+}
+
+// Show the table one page at a time, waiting for enter on in before each next page
+// Ranks keep counting across pages, so row 26 is still #26 on the second page of 25
+// Answering q, or closing in, skips the rest
+func renderPaged(w io.Writer, in io.Reader, rows []report.Row, u report.Unit, columns []tableColumn, size int) {
+	answers := bufio.NewScanner(in)
+	pages := paginate(rows, size)
+
+	for i, page := range pages {
+		header, records := tableCells(page, u, columns, i*size+1)
+
+		table := tablewriter.NewWriter(w)
+		table.SetHeader(header)
+		table.AppendBulk(records)
+		table.Render()
+
+		if i == len(pages)-1 {
+			return
+		}
+
+		fmt.Fprintf(w, "-- page %d of %d, press enter for the next page or q to stop --", i+1, len(pages))
+		if !answers.Scan() || strings.EqualFold(strings.TrimSpace(answers.Text()), "q") {
+			fmt.Fprintln(w)
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/report"
+)
+
+func pagedRows(n int) []report.Row {
+	rows := make([]report.Row, n)
+	for i := range rows {
+		rows[i].Address = fmt.Sprintf("0x%040x", i+1)
+	}
+	return rows
+}
+
+func TestPaginate(t *testing.T) {
+	for _, tc := range []struct {
+		rows, size int
+		want       []int
+	}{
+		{0, 25, []int{}},
+		{3, 25, []int{3}},
+		{50, 25, []int{25, 25}},
+		{51, 25, []int{25, 25, 1}},
+		{5, 1, []int{1, 1, 1, 1, 1}},
+	} {
+		rows := pagedRows(tc.rows)
+		pages := paginate(rows, tc.size)
+
+		sizes := []int{}
+		next := 0
+		for _, page := range pages {
+			sizes = append(sizes, len(page))
+			// Pages follow each other without gaps or repeats
+			for _, row := range page {
+				if row.Address != rows[next].Address {
+					t.Errorf("%d rows by %d: got %s at %d", tc.rows, tc.size, row.Address, next)
+				}
+				next++
+			}
+		}
+		if fmt.Sprint(sizes) != fmt.Sprint(tc.want) {
+			t.Errorf("%d rows by %d: pages of %v, want %v", tc.rows, tc.size, sizes, tc.want)
+		}
+	}
+}
+
+func TestRenderPagedKeepsRanking(t *testing.T) {
+	columns, err := parseColumns("rank,address")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	renderPaged(&out, strings.NewReader("\n\n"), pagedRows(5), report.Unit{}, columns, 2)
+
+	ranks := regexp.MustCompile(`(?m)^\|\s+(\d+)\s+\|`).FindAllStringSubmatch(out.String(), -1)
+	got := []string{}
+	for _, rank := range ranks {
+		got = append(got, rank[1])
+	}
+	if strings.Join(got, " ") != "1 2 3 4 5" {
+		t.Errorf("ranks %v, want 1 to 5 over three pages\n%s", got, out.String())
+	}
+	if prompts := strings.Count(out.String(), "press enter"); prompts != 2 {
+		t.Errorf("%d prompts, want one between each two pages", prompts)
+	}
+}
+
+func TestRenderPagedStopsOnQ(t *testing.T) {
+	columns, err := parseColumns("rank,address")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	renderPaged(&out, strings.NewReader("q\n"), pagedRows(5), report.Unit{}, columns, 2)
+
+	if strings.Contains(out.String(), pagedRows(5)[2].Address) || !strings.Contains(out.String(), "page 1 of 3") {
+		t.Errorf("went on past the first page\n%s", out.String())
+	}
+
+	// A closed input is the same as q
+	out.Reset()
+	renderPaged(&out, strings.NewReader(""), pagedRows(5), report.Unit{}, columns, 2)
+	if strings.Contains(out.String(), "page 2 of 3") {
+		t.Errorf("went on once the input was closed\n%s", out.String())
+	}
+}
@@ -0,0 +1,40 @@
+package parser
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// NormalizeAddress returns the canonical lowercase form of a hex address
+// All keys in a Result use this form
+func NormalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// ChecksumAddress returns the EIP-55 mixed case form of a hex address
+// Each letter is uppercased when the matching nibble of keccak256(lowercase hex) is 8 or more
+// Anything that isn't a 20 byte hex address is returned unchanged
+func ChecksumAddress(address string) string {
+	lower := strings.TrimPrefix(NormalizeAddress(address), "0x")
+	if len(lower) != 40 {
+		return address
+	}
+	if _, err := hex.DecodeString(lower); err != nil {
+		return address
+	}
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(lower))
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	checksummed := []byte(lower)
+	for i, c := range checksummed {
+		if c >= 'a' && c <= 'f' && digest[i] >= '8' {
+			checksummed[i] = c - 'a' + 'A'
+		}
+	}
+
+	return "0x" + string(checksummed)
+}
@@ -0,0 +1,31 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChecksumAddress(t *testing.T) {
+	// The test vectors of EIP-55
+	for _, want := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		if got := ChecksumAddress(strings.ToLower(want)); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+		if got := ChecksumAddress(strings.ToUpper(want[2:])); got != want {
+			t.Errorf("without prefix got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestChecksumAddressLeavesOtherValuesAlone(t *testing.T) {
+	for _, value := range []string{"", "0x1234", "not an address"} {
+		if got := ChecksumAddress(value); got != value {
+			t.Errorf("%q became %q", value, got)
+		}
+	}
+}
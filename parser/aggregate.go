@@ -0,0 +1,459 @@
+package parser
+
+import (
+	"math/big"
+	"sort"
+)
+
+// aggregator folds block results into running totals
+// Only the aggregation loop in Scan touches it, so it needs no locking
+type aggregator struct {
+	balances     map[string]*big.Int
+	flows        map[string]*Flow
+	tokens       map[TokenHolder]*big.Int
+	nfts         map[string]int
+	nftHolders   map[TokenHolder]*NFTFlow
+	failed       []*big.Int
+	failedHashes []string
+	completed    []*big.Int
+	watched      map[string]bool
+	excluded     map[string]bool
+	explain      string
+	explained    []Contribution
+	stats        Stats
+	gas          GasPrices
+	histogram    []int
+	headers      []Header
+
+	// The unique addresses of a Config.CountOnly scan, nil otherwise
+	addresses map[string]struct{}
+}
+
+func newAggregator(config Config) *aggregator {
+	a := &aggregator{
+		balances:   map[string]*big.Int{},
+		flows:      map[string]*Flow{},
+		tokens:     map[TokenHolder]*big.Int{},
+		nfts:       map[string]int{},
+		nftHolders: map[TokenHolder]*NFTFlow{},
+		failed:     []*big.Int{},
+		stats:      Stats{Volume: new(big.Int), Burned: new(big.Int)},
+	}
+
+	if config.CountOnly {
+		a.addresses = map[string]struct{}{}
+	}
+
+	if config.Explain != "" {
+		a.explain = NormalizeAddress(config.Explain)
+	}
+
+	if config.Histogram != nil {
+		a.histogram = make([]int, len(config.Histogram)+1)
+	}
+
+	// With a watchlist we only ever keep the watched addresses, so seed them all with zero
+	if len(config.Watchlist) > 0 {
+		a.watched = make(map[string]bool, len(config.Watchlist))
+		for _, address := range config.Watchlist {
+			address = NormalizeAddress(address)
+			a.watched[address] = true
+			if a.addresses != nil {
+				a.addresses[address] = struct{}{}
+				continue
+			}
+			a.balances[address] = new(big.Int)
+			a.flow(address)
+		}
+	}
+
+	if len(config.Exclude) > 0 {
+		a.excluded = make(map[string]bool, len(config.Exclude))
+		for _, address := range config.Exclude {
+			a.excluded[NormalizeAddress(address)] = true
+		}
+	}
+
+	return a
+}
+
+// Whether an address gets a total at all, the counterparts of skipped ones are still counted
+func (a *aggregator) keeps(address string) bool {
+	if a.watched != nil && !a.watched[address] {
+		return false
+	}
+
+	return !a.excluded[address]
+}
+
+// Fold one block into the totals, failed blocks are only remembered
+func (a *aggregator) add(result blockResult) {
+	if result.err != nil && result.block == nil {
+		a.failedHashes = append(a.failedHashes, result.hash)
+		return
+	}
+	if result.err != nil {
+		a.failed = append(a.failed, result.block)
+		return
+	}
+
+	a.completed = append(a.completed, result.block)
+	a.headers = append(a.headers, Header{Number: result.block, Hash: result.hash, ParentHash: result.parentHash, BaseFee: result.baseFee})
+	a.stats.Transactions += result.transactions
+	a.stats.Transfers += result.transfers
+	a.stats.Volume.Add(a.stats.Volume, &result.volume)
+	a.stats.Burned.Add(a.stats.Burned, &result.burned)
+	a.stats.Largest = largerTransfer(a.stats.Largest, result.largest)
+	a.gas.merge(result.gas, 1)
+	for i, count := range result.histogram {
+		a.histogram[i] += count
+	}
+
+	// Process each change from the chunk
+	// The totals are updated in place and only allocated the first time an address shows up
+	// Indexing avoids copying every change, the big.Int inside is read straight from the slice
+	for i := range result.changes {
+		balanceChange := &result.changes[i]
+		if !a.keeps(balanceChange.Address) {
+			continue
+		}
+
+		// Counting an address is all a count only scan does with it
+		if a.addresses != nil {
+			a.addresses[balanceChange.Address] = struct{}{}
+			continue
+		}
+
+		a.addBalance(balanceChange.Address, &balanceChange.Balance)
+
+		// Debits count as sent and credits as received, so Received - Sent is always the net
+		flow := a.flow(balanceChange.Address)
+		if balanceChange.Balance.Sign() < 0 {
+			flow.Sent.Sub(flow.Sent, &balanceChange.Balance)
+		} else {
+			flow.Received.Add(flow.Received, &balanceChange.Balance)
+		}
+	}
+
+	for address, count := range result.participants {
+		if !a.keeps(address) || a.addresses != nil {
+			continue
+		}
+
+		a.flow(address).Transactions += count
+	}
+
+	// Holders go through the watchlist and exclusions like ETH addresses do
+	for _, tokenChange := range result.tokens {
+		if !a.keeps(tokenChange.Holder) {
+			continue
+		}
+		a.addToken(tokenChange.TokenHolder, &tokenChange.Amount)
+	}
+
+	for _, nft := range result.nfts {
+		a.addNFT(nft)
+	}
+
+	// The changes only add up to a total the address actually gets
+	if a.explain != "" && a.keeps(a.explain) {
+		a.explained = append(a.explained, result.explained...)
+	}
+}
+
+// The net change of every address the totals keep, summed over the transactions of one block
+func (a *aggregator) netChanges(result blockResult) map[string]*big.Int {
+	changes := map[string]*big.Int{}
+	for i := range result.changes {
+		change := &result.changes[i]
+		if !a.keeps(change.Address) {
+			continue
+		}
+
+		net, ok := changes[change.Address]
+		if !ok {
+			net = new(big.Int)
+			changes[change.Address] = net
+		}
+		net.Add(net, &change.Balance)
+	}
+
+	return changes
+}
+
+func (a *aggregator) addBalance(address string, change *big.Int) {
+	balance, ok := a.balances[address]
+	if !ok {
+		balance = new(big.Int)
+		a.balances[address] = balance
+	}
+
+	balance.Add(balance, change)
+}
+
+func (a *aggregator) flow(address string) *Flow {
+	flow, ok := a.flows[address]
+	if !ok {
+		flow = &Flow{Sent: new(big.Int), Received: new(big.Int)}
+		a.flows[address] = flow
+	}
+
+	return flow
+}
+
+func (a *aggregator) addToken(holder TokenHolder, change *big.Int) {
+	amount, ok := a.tokens[holder]
+	if !ok {
+		amount = new(big.Int)
+		a.tokens[holder] = amount
+	}
+
+	amount.Add(amount, change)
+}
+
+// Count an ERC-721 transfer for its collection and each holder the totals keep, a transfer between two left out holders is not counted at all
+func (a *aggregator) addNFT(nft NFTTransfer) {
+	from, to := a.keeps(nft.From), a.keeps(nft.To)
+	if !from && !to {
+		return
+	}
+	a.nfts[nft.Collection]++
+
+	if from {
+		a.nftFlow(TokenHolder{Token: nft.Collection, Holder: nft.From}).Sent++
+	}
+	if to {
+		a.nftFlow(TokenHolder{Token: nft.Collection, Holder: nft.To}).Received++
+	}
+}
+
+func (a *aggregator) nftFlow(holder TokenHolder) *NFTFlow {
+	flow, ok := a.nftHolders[holder]
+	if !ok {
+		flow = &NFTFlow{}
+		a.nftHolders[holder] = flow
+	}
+
+	return flow
+}
+
+// Merge adds the totals of other into r
+// The two scans must cover different blocks, otherwise those blocks are counted twice
+func (r *Result) Merge(other *Result) {
+	r.merge(other, 1)
+}
+
+// Subtract takes the totals of other, a scan whose blocks were merged into r earlier, back out of r
+// This is how watch mode undoes blocks that a reorg removed from the chain
+func (r *Result) Subtract(other *Result) {
+	r.merge(other, -1)
+}
+
+func (r *Result) merge(other *Result, sign int) {
+	if r.Balances == nil {
+		r.Balances = map[string]*big.Int{}
+	}
+	if r.Flows == nil {
+		r.Flows = map[string]*Flow{}
+	}
+	if r.Tokens == nil {
+		r.Tokens = map[TokenHolder]*big.Int{}
+	}
+	if r.TokenInfo == nil {
+		r.TokenInfo = map[string]TokenInfo{}
+	}
+	if r.NFTs == nil {
+		r.NFTs = map[string]int{}
+	}
+	if r.NFTHolders == nil {
+		r.NFTHolders = map[TokenHolder]*NFTFlow{}
+	}
+	if r.Stats.Volume == nil {
+		r.Stats.Volume = new(big.Int)
+	}
+	if r.Stats.Burned == nil {
+		r.Stats.Burned = new(big.Int)
+	}
+
+	for address, change := range other.Balances {
+		addSigned(r.Balances, address, change, sign)
+	}
+
+	for address, flow := range other.Flows {
+		mine, ok := r.Flows[address]
+		if !ok {
+			mine = &Flow{Sent: new(big.Int), Received: new(big.Int)}
+			r.Flows[address] = mine
+		}
+
+		mine.Sent.Add(mine.Sent, signed(flow.Sent, sign))
+		mine.Received.Add(mine.Received, signed(flow.Received, sign))
+		mine.Transactions += sign * flow.Transactions
+	}
+
+	// Taking back the only blocks an address showed up in leaves nothing of it, just like a scan that never saw them
+	if sign < 0 {
+		for address := range other.Balances {
+			r.dropIdle(address)
+		}
+		for address := range other.Flows {
+			r.dropIdle(address)
+		}
+	}
+
+	// The addresses of count only results are gone, so only full results keep an exact count
+	r.Addresses = len(r.Balances)
+
+	for holder, change := range other.Tokens {
+		addSigned(r.Tokens, holder, change, sign)
+	}
+
+	for collection, transfers := range other.NFTs {
+		r.NFTs[collection] += sign * transfers
+	}
+
+	for holder, flow := range other.NFTHolders {
+		mine, ok := r.NFTHolders[holder]
+		if !ok {
+			mine = &NFTFlow{}
+			r.NFTHolders[holder] = mine
+		}
+
+		mine.Sent += sign * flow.Sent
+		mine.Received += sign * flow.Received
+	}
+
+	if sign < 0 {
+		for holder := range other.NFTHolders {
+			if flow := r.NFTHolders[holder]; flow.Sent == 0 && flow.Received == 0 {
+				delete(r.NFTHolders, holder)
+			}
+		}
+		for collection := range other.NFTs {
+			if r.NFTs[collection] == 0 {
+				delete(r.NFTs, collection)
+			}
+		}
+	}
+
+	for token, info := range other.TokenInfo {
+		r.TokenInfo[token] = info
+	}
+
+	if sign > 0 {
+		r.Failed = append(r.Failed, other.Failed...)
+		r.FailedHashes = append(r.FailedHashes, other.FailedHashes...)
+		sort.Slice(r.Failed, func(i, j int) bool { return r.Failed[i].Cmp(r.Failed[j]) < 0 })
+
+		r.Headers = append(r.Headers, other.Headers...)
+		sort.Slice(r.Headers, func(i, j int) bool { return r.Headers[i].Number.Cmp(r.Headers[j].Number) < 0 })
+	} else {
+		removed := map[string]bool{}
+		for _, header := range other.Headers {
+			removed[header.Hash] = true
+		}
+
+		kept := r.Headers[:0]
+		for _, header := range r.Headers {
+			if !removed[header.Hash] {
+				kept = append(kept, header)
+			}
+		}
+		r.Headers = kept
+	}
+
+	r.Gas.merge(other.Gas, sign)
+
+	if sign > 0 {
+		r.Explained = append(r.Explained, other.Explained...)
+		sortContributions(r.Explained)
+	} else {
+		removed := map[string]bool{}
+		for _, contribution := range other.Explained {
+			removed[contribution.Hash] = true
+		}
+
+		kept := r.Explained[:0]
+		for _, contribution := range r.Explained {
+			if !removed[contribution.Hash] {
+				kept = append(kept, contribution)
+			}
+		}
+		r.Explained = kept
+	}
+
+	if r.Histogram == nil && other.Histogram != nil {
+		r.Histogram = make([]int, len(other.Histogram))
+	}
+	for i, count := range other.Histogram {
+		r.Histogram[i] += sign * count
+	}
+
+	// Watch mode keeps merging the next blocks, so the range grows to cover them
+	// Calls stay spent even when their blocks are taken back out
+	if sign > 0 {
+		if r.From == nil || other.From != nil && other.From.Cmp(r.From) < 0 {
+			r.From = other.From
+		}
+		if r.To == nil || other.To != nil && other.To.Cmp(r.To) > 0 {
+			r.To = other.To
+		}
+
+		r.Calls += other.Calls
+		r.Sampled = r.Sampled || other.Sampled
+		r.Pending += other.Pending
+	}
+
+	r.Blocks += sign * other.Blocks
+	r.Stats.Transactions += sign * other.Stats.Transactions
+	r.Stats.Transfers += sign * other.Stats.Transfers
+	if other.Stats.Volume != nil {
+		r.Stats.Volume.Add(r.Stats.Volume, signed(other.Stats.Volume, sign))
+	}
+	if other.Stats.Burned != nil {
+		r.Stats.Burned.Add(r.Stats.Burned, signed(other.Stats.Burned, sign))
+	}
+
+	// The runner up isn't kept, so a largest transfer that is taken back out leaves no largest at all
+	// until a bigger one comes along
+	if sign > 0 {
+		r.Stats.Largest = largerTransfer(r.Stats.Largest, other.Stats.Largest)
+	} else if r.Stats.Largest != nil && other.Stats.Largest != nil && r.Stats.Largest.Hash == other.Stats.Largest.Hash {
+		r.Stats.Largest = nil
+	}
+}
+
+// Remove an address whose total is back to zero and that has no flow left
+func (r *Result) dropIdle(address string) {
+	if balance, ok := r.Balances[address]; ok && balance.Sign() != 0 {
+		return
+	}
+	if flow, ok := r.Flows[address]; ok && (flow.Sent.Sign() != 0 || flow.Received.Sign() != 0 || flow.Transactions != 0) {
+		return
+	}
+
+	delete(r.Balances, address)
+	delete(r.Flows, address)
+}
+
+func addSigned[K comparable](totals map[K]*big.Int, key K, change *big.Int, sign int) {
+	if total, ok := totals[key]; ok {
+		total.Add(total, signed(change, sign))
+	} else {
+		totals[key] = new(big.Int).Set(signed(change, sign))
+	}
+}
+
+// change itself when adding, a negated copy when subtracting so the caller's value is never mutated
+func signed(change *big.Int, sign int) *big.Int {
+	if sign < 0 {
+		return new(big.Int).Neg(change)
+	}
+
+	return change
+}
+
+// Index of the histogram bucket value falls into, bounds are ascending upper bounds
+func bucket(bounds []*big.Int, value *big.Int) int {
+	return sort.Search(len(bounds), func(i int) bool { return value.Cmp(bounds[i]) < 0 })
+}
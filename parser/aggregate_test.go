@@ -0,0 +1,247 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ofen/getblock-go/eth"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+func TestWatchlistKeepsOnlyWatchedAddresses(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 5}},
+		2: {{from: bob, to: alice, value: 2}},
+	}}
+
+	// Matched whatever the casing, and a watched address without activity still shows up
+	result := scan(t, chain, 1, 2, Config{Workers: 1, Watchlist: []string{strings.ToUpper(bob), carol}})
+
+	wantBalances(t, result, map[string]int64{bob: 3, carol: 0})
+}
+
+func TestContractCreationOnlyDebitsTheSender(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, value: 7, gasUsed: 100, gasPrice: 1}},
+	}}
+
+	result := scan(t, chain, 1, 1, Config{Workers: 1, IncludeGas: true})
+
+	// The value went into the new contract, there is no row for an empty address
+	wantBalances(t, result, map[string]int64{alice: -107})
+	if _, ok := result.Flows[""]; ok {
+		t.Error("flows have an empty address")
+	}
+}
+
+func TestAddressCasingsShareOneTotal(t *testing.T) {
+	mixed := "0x000000000000000000000000000000000000000B"
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 5}},
+		2: {{from: alice, to: mixed, value: 3}},
+		3: {{from: mixed, to: alice, value: 1}},
+	}}
+
+	result := scan(t, chain, 1, 3, Config{Workers: 2})
+
+	wantBalances(t, result, map[string]int64{alice: -7, bob: 7})
+	if flow := result.Flows[bob]; flow.Transactions != 3 {
+		t.Errorf("bob's flow %+v, want all three transactions", flow)
+	}
+}
+
+// A block of n transfers between a pool of 100 addresses, like a busy block on a chain that has seen them all before
+func busyBlock(n int) blockResult {
+	result := blockResult{block: big.NewInt(1), participants: map[string]int{}}
+	for i := 0; i < n; i++ {
+		from, to := fmt.Sprintf("0x%040x", i%100), fmt.Sprintf("0x%040x", (i+1)%100)
+		result.changes = append(result.changes, BalanceChange{Address: from, Balance: *big.NewInt(-int64(i))}, BalanceChange{Address: to, Balance: *big.NewInt(int64(i))})
+		result.participants[from]++
+		result.participants[to]++
+	}
+	return result
+}
+
+// Addresses already in the totals are updated in place, only the growing list of finished blocks allocates now and then
+func TestAggregationReusesTotals(t *testing.T) {
+	a := newAggregator(Config{})
+	block := busyBlock(1000)
+	a.add(block)
+
+	allocs := testing.AllocsPerRun(100, func() { a.add(block) })
+	if allocs > 1 {
+		t.Errorf("adding a block of known addresses took %.0f allocations, want at most one whatever its size", allocs)
+	}
+}
+
+// go test -bench Aggregate -benchmem ./parser
+func BenchmarkAggregate(b *testing.B) {
+	a := newAggregator(Config{})
+	block := busyBlock(1000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a.add(block)
+	}
+}
+
+// Changing the value of a transaction after its block was parsed must not reach the totals
+func TestStoredChangesDontAliasTransactionValues(t *testing.T) {
+	value := big.NewInt(5)
+	block := &eth.Block{Hash: "0x01", Transactions: []eth.Transaction{{Hash: "0x02", From: alice, To: bob, Value: value}}}
+
+	s := newScanner(nil, Config{Workers: 1})
+	result := s.parseBlock(context.Background(), big.NewInt(1), block)
+
+	a := newAggregator(Config{})
+	a.add(result)
+
+	value.SetInt64(1000)
+	for i := range result.changes {
+		result.changes[i].Balance.SetInt64(1000)
+	}
+
+	if got := a.balances[bob]; got.Int64() != 5 {
+		t.Errorf("bob's total became %s", got)
+	}
+	if got := a.flows[alice].Sent; got.Int64() != 5 {
+		t.Errorf("alice's sent total became %s", got)
+	}
+	if got := a.stats.Largest.Value; got.Int64() != 5 {
+		t.Errorf("the largest transfer became %s", got)
+	}
+	if got := a.stats.Volume; got.Int64() != 5 {
+		t.Errorf("the volume became %s", got)
+	}
+}
+
+func TestHistogramBucketsTransfers(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 5}, {from: alice, to: bob, value: 10}},
+		2: {{from: alice, to: bob, value: 99}, {from: alice, to: bob, value: 100}, {from: alice, to: bob, value: 1000}},
+		// Zero value calls move nothing, so they are no transfer to bucket
+		3: {{from: alice, to: bob, value: 0}},
+	}}
+
+	// A value on a bound goes into the bucket above it
+	result := scan(t, chain, 1, 3, Config{Workers: 2, Histogram: []*big.Int{big.NewInt(10), big.NewInt(100)}})
+
+	if got := fmt.Sprint(result.Histogram); got != "[1 2 2]" {
+		t.Errorf("histogram %s, want [1 2 2]", got)
+	}
+}
+
+func TestExcludedAddressesGetNoRow(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 5}},
+		2: {{from: bob, to: carol, value: 2}},
+	}}
+
+	// Bob's row goes, what alice sent him and carol got from him still counts
+	result := scan(t, chain, 1, 2, Config{Workers: 2, Exclude: []string{"0x" + strings.ToUpper(bob[2:])}})
+
+	wantBalances(t, result, map[string]int64{alice: -5, carol: 2})
+	if _, ok := result.Flows[bob]; ok {
+		t.Error("bob still has a flow")
+	}
+}
+
+func TestBetweenKeepsOnlyTheirTransactions(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 10}, {from: alice, to: carol, value: 100}},
+		2: {{from: bob, to: alice, value: 3}, {from: carol, to: bob, value: 50}},
+	}}
+
+	result := scan(t, chain, 1, 2, Config{Workers: 2, Between: []string{bob, alice}})
+
+	wantBalances(t, result, map[string]int64{alice: -7, bob: 7})
+	if flow := result.Flows[alice]; flow.Sent.Int64() != 10 || flow.Received.Int64() != 3 || flow.Transactions != 2 {
+		t.Errorf("alice's flow %+v", flow)
+	}
+}
+
+func TestCountOnlyMatchesAFullScan(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 5, gasUsed: 1, gasPrice: 2}, {from: bob, to: carol, value: 0}},
+		2: {{from: carol, value: 3}},
+		3: {{from: carol, to: alice, value: 9}, {from: "0x000000000000000000000000000000000000000d", to: bob, value: 1}},
+	}}
+
+	for _, config := range []Config{{Workers: 2}, {Workers: 2, IncludeGas: true}, {Workers: 2, Watchlist: []string{alice, carol}}} {
+		full := scan(t, chain, 1, 5, config)
+
+		config.CountOnly = true
+		counted := scan(t, chain, 1, 5, config)
+
+		if len(counted.Balances) != 0 || len(counted.Flows) != 0 {
+			t.Errorf("count only kept %d totals and %d flows", len(counted.Balances), len(counted.Flows))
+		}
+		if counted.Addresses != len(full.Balances) || counted.Addresses != full.Addresses {
+			t.Errorf("%d unique addresses, the full scan has %d", counted.Addresses, len(full.Balances))
+		}
+		if counted.Stats.Transactions != full.Stats.Transactions || counted.Stats.Transfers != full.Stats.Transfers ||
+			counted.Stats.Volume.Cmp(full.Stats.Volume) != 0 {
+			t.Errorf("stats %+v, the full scan has %+v", counted.Stats, full.Stats)
+		}
+		if counted.Stats.Largest.Hash != full.Stats.Largest.Hash {
+			t.Errorf("largest %+v, the full scan has %+v", counted.Stats.Largest, full.Stats.Largest)
+		}
+	}
+}
+
+// Every receipt carries a USDC transfer from the sender to the receiver and a punk going the other way
+type tokenLogChain struct {
+	fakeChain
+}
+
+func (c *tokenLogChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	if method == "eth_call" {
+		return &jsonrpc.RPCResponse{Error: &jsonrpc.RPCError{Code: -32000, Message: "execution reverted"}}, nil
+	}
+
+	r, err := c.fakeChain.Call(ctx, method, params...)
+	if err != nil || method != "eth_getTransactionReceipt" {
+		return r, err
+	}
+
+	receipt := r.Result.(map[string]interface{})
+	from, to := "0x"+word(receipt["from"].(string)), "0x"+word(receipt["to"].(string))
+	receipt["logs"] = []interface{}{
+		map[string]interface{}{"address": usdc, "topics": []string{transferTopic, from, to}, "data": "0x" + word("64")},
+		map[string]interface{}{"address": punks, "topics": []string{transferTopic, to, from, "0x" + word("1")}, "data": "0x"},
+	}
+	return r, nil
+}
+
+func TestWatchlistAppliesToTokenHolders(t *testing.T) {
+	chain := &tokenLogChain{fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 1}},
+		2: {{from: bob, to: carol, value: 1}},
+	}}}
+
+	result := scan(t, chain, 1, 2, Config{Workers: 2, Tokens: true, Watchlist: []string{bob}})
+
+	// Only bob's side of each transfer is kept, alice and carol have no token rows
+	if len(result.Tokens) != 1 || result.Tokens[TokenHolder{Token: usdc, Holder: bob}].Sign() != 0 {
+		t.Errorf("tokens %v, want only bob's, who got and sent on 100", result.Tokens)
+	}
+	if len(result.NFTHolders) != 1 {
+		t.Errorf("NFT holders %v, want only bob", result.NFTHolders)
+	}
+	if flow := result.NFTHolders[TokenHolder{Token: punks, Holder: bob}]; flow == nil || *flow != (NFTFlow{Sent: 1, Received: 1}) {
+		t.Errorf("bob's punks %+v", flow)
+	}
+	if result.NFTs[punks] != 2 {
+		t.Errorf("%d punk transfers, want both of bob's", result.NFTs[punks])
+	}
+
+	// A transfer between two addresses that are not watched is not counted at all
+	result = scan(t, chain, 1, 1, Config{Workers: 1, Tokens: true, Watchlist: []string{carol}})
+	if len(result.Tokens) != 0 || len(result.NFTHolders) != 0 || result.NFTs[punks] != 0 {
+		t.Errorf("tokens %v, NFTs %v %v for an unwatched transfer", result.Tokens, result.NFTs, result.NFTHolders)
+	}
+}
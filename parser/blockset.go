@@ -0,0 +1,61 @@
+package parser
+
+import (
+	"math/big"
+	"sync"
+)
+
+// BlockSet remembers which blocks have been counted, it is safe for concurrent use
+// Scans sharing one set never count a block twice, however the block was discovered
+// A nil *BlockSet is empty and remembers nothing
+type BlockSet struct {
+	mu     sync.Mutex
+	blocks map[string]bool
+}
+
+// NewBlockSet returns an empty set
+func NewBlockSet() *BlockSet {
+	return &BlockSet{blocks: map[string]bool{}}
+}
+
+// Contains reports whether block has been counted already
+func (b *BlockSet) Contains(block *big.Int) bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.blocks[block.String()]
+}
+
+// Remove forgets block, so a later scan sharing the set counts it again
+func (b *BlockSet) Remove(block *big.Int) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.blocks, block.String())
+}
+
+// Add marks block as counted, it returns false if it already was
+func (b *BlockSet) Add(block *big.Int) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := block.String()
+	if b.blocks[key] {
+		return false
+	}
+	b.blocks[key] = true
+
+	return true
+}
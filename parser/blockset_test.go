@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBlockSetAddsOnce(t *testing.T) {
+	set := NewBlockSet()
+
+	// Many goroutines racing for the same block, exactly one wins
+	var wg sync.WaitGroup
+	var added atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if set.Add(big.NewInt(7)) {
+				added.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := added.Load(); got != 1 {
+		t.Errorf("block 7 added %d times", got)
+	}
+	if !set.Contains(big.NewInt(7)) || set.Contains(big.NewInt(8)) {
+		t.Error("set holds the wrong blocks")
+	}
+
+	set.Remove(big.NewInt(7))
+	if !set.Add(big.NewInt(7)) {
+		t.Error("a removed block can't be added again")
+	}
+}
+
+// The same block enqueued by two scans sharing a set, like a backfill and a new head, counts once
+func TestSharedBlockSetCountsABlockOnce(t *testing.T) {
+	chain := &fakeChain{}
+	config := Config{Workers: 2, Seen: NewBlockSet()}
+
+	first := scan(t, chain, 1, 3, config)
+	second := scan(t, chain, 3, 4, config)
+
+	// Block 3 is in both ranges but only the first scan counts it
+	wantBalances(t, first, map[string]int64{alice: -6, bob: 6})
+	wantBalances(t, second, map[string]int64{alice: -4, bob: 4})
+	if got := chain.fetched(3); got != 1 {
+		t.Errorf("block 3 fetched %d times, want 1", got)
+	}
+}
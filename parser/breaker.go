@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// States of the circuit breaker
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerProbing
+)
+
+// A circuit breaker shared by every worker of a scan
+// After threshold failed calls in a row it opens and holds all calls back for cooldown
+// Then a single probe goes out, success closes the breaker again and failure opens it for another cooldown
+// A zero threshold disables it
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	log       *slog.Logger
+
+	mu        sync.Mutex
+	state     int
+	failures  int
+	openUntil time.Time
+
+	// Closed and replaced on every state change, so waiting calls can check again
+	changed chan struct{}
+}
+
+func newBreaker(threshold int, cooldown time.Duration, log *slog.Logger) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, log: log, changed: make(chan struct{})}
+}
+
+// Block until a call may go out, or ctx is done
+// Once the cooldown is over the first caller gets to send the probe, the rest wait for its outcome
+func (b *breaker) wait(ctx context.Context) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	for {
+		b.mu.Lock()
+		state, until, changed := b.state, b.openUntil, b.changed
+
+		if state == breakerClosed {
+			b.mu.Unlock()
+			return nil
+		}
+		if state == breakerOpen && !time.Now().Before(until) {
+			b.setState(breakerProbing)
+			b.mu.Unlock()
+			return nil
+		}
+		b.mu.Unlock()
+
+		// Open waits out the cooldown, probing waits for the probe
+		var cooledDown <-chan time.Time
+		var timer *time.Timer
+		if state == breakerOpen {
+			timer = time.NewTimer(time.Until(until))
+			cooledDown = timer.C
+		}
+
+		select {
+		case <-cooledDown:
+		case <-changed:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// Record the outcome of a call that wait let through
+func (b *breaker) record(err error) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if b.state != breakerClosed {
+			b.log.Info("RPC endpoint is answering again, resuming requests")
+			b.setState(breakerClosed)
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+
+	// A failed probe means the endpoint still isn't back
+	if b.state == breakerProbing || b.state == breakerClosed && b.failures >= b.threshold {
+		b.log.Warn("RPC endpoint keeps failing, pausing requests", "failures", b.failures, "cooldown", b.cooldown)
+		b.openUntil = time.Now().Add(b.cooldown)
+		b.setState(breakerOpen)
+	}
+}
+
+// Callers hold b.mu
+func (b *breaker) setState(state int) {
+	b.state = state
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
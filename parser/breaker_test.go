@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("node unavailable")
+
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestBreakerOpensAndClosesAgain(t *testing.T) {
+	b := newBreaker(3, 50*time.Millisecond, quietLogger())
+
+	// Failures below the threshold, or broken up by a success, keep calls going
+	for _, err := range []error{errDown, errDown, nil, errDown, errDown} {
+		b.record(err)
+	}
+	if b.state != breakerClosed {
+		t.Fatalf("state %d after two failures in a row, want closed", b.state)
+	}
+
+	b.record(errDown)
+	if b.state != breakerOpen {
+		t.Fatalf("state %d after three failures in a row, want open", b.state)
+	}
+
+	// Calls are held back for the cooldown, then one goes out as the probe
+	start := time.Now()
+	if err := b.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < 40*time.Millisecond {
+		t.Errorf("let through after %v, before the cooldown was over", took)
+	}
+	if b.state != breakerProbing {
+		t.Fatalf("state %d after the cooldown, want probing", b.state)
+	}
+
+	// A failed probe opens it for another cooldown, a good one closes it
+	b.record(errDown)
+	if b.state != breakerOpen {
+		t.Fatalf("state %d after a failed probe, want open", b.state)
+	}
+	if err := b.wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	b.record(nil)
+	if b.state != breakerClosed {
+		t.Fatalf("state %d after a good probe, want closed", b.state)
+	}
+}
+
+func TestBreakerSendsASingleProbe(t *testing.T) {
+	b := newBreaker(1, 10*time.Millisecond, quietLogger())
+	b.record(errDown)
+
+	var through atomic.Int32
+	done := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		go func() {
+			if b.wait(context.Background()) == nil {
+				through.Add(1)
+			}
+			done <- struct{}{}
+		}()
+	}
+
+	// Only the probe is out until its outcome is known
+	time.Sleep(100 * time.Millisecond)
+	if got := through.Load(); got != 1 {
+		t.Fatalf("%d calls let through while probing, want 1", got)
+	}
+
+	b.record(nil)
+	for i := 0; i < 5; i++ {
+		<-done
+	}
+	if got := through.Load(); got != 5 {
+		t.Errorf("%d calls let through after the probe, want 5", got)
+	}
+}
+
+func TestOpenBreakerGivesWayToCancellation(t *testing.T) {
+	b := newBreaker(1, time.Hour, quietLogger())
+	b.record(errDown)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want the deadline", err)
+	}
+}
+
+func TestDisabledBreakerNeverOpens(t *testing.T) {
+	b := newBreaker(0, time.Hour, quietLogger())
+	for i := 0; i < 100; i++ {
+		b.record(errDown)
+	}
+	if err := b.wait(context.Background()); err != nil || b.state != breakerClosed {
+		t.Errorf("got %v in state %d", err, b.state)
+	}
+}
+
+func TestScanRecoversOnceTheBreakerCloses(t *testing.T) {
+	// Block 1 fails four times in a row, enough to open the breaker twice, then the node is back
+	chain := &fakeChain{failures: map[uint64]int{1: 4}}
+	config := Config{Workers: 1, Retries: 5, RetryDelay: time.Millisecond, BreakerThreshold: 2, BreakerCooldown: 20 * time.Millisecond, Logger: quietLogger()}
+
+	start := time.Now()
+	result := scan(t, chain, 1, 2, config)
+
+	wantBalances(t, result, map[string]int64{alice: -3, bob: 3})
+	if len(result.Failed) != 0 {
+		t.Errorf("failed %v", result.Failed)
+	}
+	if took := time.Since(start); took < 40*time.Millisecond {
+		t.Errorf("took %v, the breaker never held calls back", took)
+	}
+}
@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// London activates at block 3, from there block n has a base fee of n gwei and used 21000n gas
+type londonChain struct {
+	fakeChain
+}
+
+func (c *londonChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	r, err := c.fakeChain.Call(ctx, method, params...)
+	if err != nil || method != "eth_getBlockByNumber" {
+		return r, err
+	}
+
+	block := r.Result.(map[string]interface{})
+	var n uint64
+	fmt.Sscanf(block["number"].(string), "0x%x", &n)
+
+	block["gasUsed"] = fmt.Sprintf("%#x", 21000*n)
+	if n >= 3 {
+		block["baseFeePerGas"] = fmt.Sprintf("%#x", n*1000000000)
+	}
+	return r, nil
+}
+
+func TestBurnedAddsUpBaseFeeTimesGasUsed(t *testing.T) {
+	result := scan(t, &londonChain{}, 1, 4, Config{Workers: 2})
+
+	// Blocks 1 and 2 are from before London and burn nothing
+	want := int64(3*1000000000*21000*3 + 4*1000000000*21000*4)
+	if result.Stats.Burned == nil || result.Stats.Burned.Int64() != want {
+		t.Errorf("burned %v, want %d", result.Stats.Burned, want)
+	}
+
+	if result := scan(t, &londonChain{}, 1, 2, Config{Workers: 1}); result.Stats.Burned.Sign() != 0 {
+		t.Errorf("burned %v before London", result.Stats.Burned)
+	}
+}
+
+func TestBurnedCoversWholeBlocks(t *testing.T) {
+	chain := &londonChain{fakeChain{txs: map[uint64][]fakeTx{
+		3: {{from: alice, to: bob, value: 1}, {from: carol, to: bob, value: 2}},
+	}}}
+
+	// Only carol is watched, the burn still counts the gas of every transaction in the block
+	result := scan(t, chain, 3, 3, Config{Workers: 1, Watchlist: []string{carol}})
+	if want := int64(3 * 1000000000 * 21000 * 3); result.Stats.Burned.Int64() != want {
+		t.Errorf("burned %v, want %d", result.Stats.Burned, want)
+	}
+}
@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+)
+
+// blockCache keeps the raw JSON of fetched blocks on disk as one gzip file per block
+// Finalized blocks never change, so a cached copy is as good as a fresh one
+type blockCache struct {
+	dir string
+}
+
+// Every network gets its own subdirectory, block 100 of one chain is nothing like block 100 of another
+func newBlockCache(dir, network string) blockCache {
+	if dir == "" || network == "" {
+		return blockCache{dir: dir}
+	}
+
+	return blockCache{dir: filepath.Join(dir, network)}
+}
+
+func (c blockCache) path(blockNum *big.Int) string {
+	return filepath.Join(c.dir, fmt.Sprintf("%d.json.gz", blockNum))
+}
+
+// Return the cached block JSON, or false if the block isn't cached or the file is unreadable
+func (c blockCache) get(blockNum *big.Int) ([]byte, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+
+	file, err := os.Open(c.path(blockNum))
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, false
+	}
+	defer reader.Close()
+
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, false
+	}
+
+	return raw, true
+}
+
+// Write the block JSON to a temp file and rename it into place
+// Concurrent readers never see a half written file and a crash leaves no corrupt entries behind
+func (c blockCache) put(blockNum *big.Int, raw []byte) error {
+	if c.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(c.dir, ".block-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	writer := gzip.NewWriter(file)
+	if _, err := writer.Write(raw); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), c.path(blockNum))
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	cache := newBlockCache(t.TempDir(), "mainnet")
+
+	if _, ok := cache.get(big.NewInt(7)); ok {
+		t.Fatal("empty cache returned a block")
+	}
+	if err := cache.put(big.NewInt(7), []byte(`{"number":"0x7"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, ok := cache.get(big.NewInt(7))
+	if !ok || string(raw) != `{"number":"0x7"}` {
+		t.Fatalf("got %q, %v", raw, ok)
+	}
+}
+
+func TestCacheKeepsNetworksApart(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := newBlockCache(dir, "mainnet").put(big.NewInt(7), []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := newBlockCache(dir, "polygon").get(big.NewInt(7)); ok {
+		t.Fatal("polygon read the mainnet block from the shared cache directory")
+	}
+}
+
+func TestCheckpointNeedsSameNetwork(t *testing.T) {
+	from, to := big.NewInt(1), big.NewInt(10)
+
+	mainnet := newCheckpoint(from, to, Config{Network: "mainnet"})
+	if !mainnet.sameScan(newCheckpoint(from, to, Config{Network: "mainnet"})) {
+		t.Fatal("identical scans don't match")
+	}
+	if mainnet.sameScan(newCheckpoint(from, to, Config{Network: "polygon"})) {
+		t.Fatal("a mainnet checkpoint matched a polygon scan")
+	}
+}
+
+func TestWarmCacheMakesNoCalls(t *testing.T) {
+	config := Config{Workers: 2, CacheDir: t.TempDir(), Network: "mainnet", CacheFinalized: big.NewInt(100)}
+
+	first := scan(t, &fakeChain{}, 1, 10, config)
+	second := scan(t, &fakeChain{}, 1, 10, config)
+
+	if first.Calls != 10 || second.Calls != 0 {
+		t.Errorf("calls %d then %d, want 10 then 0", first.Calls, second.Calls)
+	}
+	wantBalances(t, second, map[string]int64{alice: -55, bob: 55})
+}
+
+func TestBlocksNearTheHeadAreNotCached(t *testing.T) {
+	config := Config{Workers: 2, CacheDir: t.TempDir(), Network: "mainnet", CacheFinalized: big.NewInt(5)}
+
+	scan(t, &fakeChain{}, 1, 10, config)
+
+	// Blocks 6 to 10 may still be reorged away, so they are fetched again
+	if second := scan(t, &fakeChain{}, 1, 10, config); second.Calls != 5 {
+		t.Errorf("second scan made %d calls, want 5", second.Calls)
+	}
+}
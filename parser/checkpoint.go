@@ -0,0 +1,338 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// On disk state of an interrupted scan
+// The settings that change what gets counted are stored too, a checkpoint is only reused when they match
+type checkpoint struct {
+	From       *big.Int          `json:"from"`
+	To         *big.Int          `json:"to"`
+	Network    string            `json:"network,omitempty"`
+	IncludeGas bool              `json:"include_gas"`
+	Tokens     bool              `json:"tokens"`
+	Watchlist  []string          `json:"watchlist,omitempty"`
+	Exclude    []string          `json:"exclude,omitempty"`
+	Between    []string          `json:"between,omitempty"`
+	TxMinWei   string            `json:"tx_min_wei,omitempty"`
+	ZeroValue  bool              `json:"include_zero,omitempty"`
+	Trace      bool              `json:"trace,omitempty"`
+	CountsOnly bool              `json:"tx_counts_only,omitempty"`
+	TotalsOnly bool              `json:"count_only,omitempty"`
+	GasStats   bool              `json:"gas_stats,omitempty"`
+	Buckets    []*big.Int        `json:"histogram_buckets,omitempty"`
+	Sample     int               `json:"sample,omitempty"`
+	Explain    string            `json:"explain,omitempty"`
+	Explained  []Contribution    `json:"explained,omitempty"`
+	Seed       int64             `json:"seed,omitempty"`
+	Completed  []*big.Int        `json:"completed"`
+	Balances   map[string]string `json:"balances"`
+	Sent       map[string]string `json:"sent"`
+	Received   map[string]string `json:"received"`
+	TxCounts   map[string]int    `json:"tx_counts"`
+	Addresses  []string          `json:"addresses,omitempty"`
+	TokenTotal []tokenTotal      `json:"token_balances,omitempty"`
+	NFTs       map[string]int    `json:"nft_transfers,omitempty"`
+	NFTHolders []nftTotal        `json:"nft_holders,omitempty"`
+	GasPrices  *GasPrices        `json:"gas_prices,omitempty"`
+	Histogram  []int             `json:"histogram,omitempty"`
+
+	Transactions int       `json:"transactions"`
+	Transfers    int       `json:"transfers"`
+	Volume       string    `json:"volume"`
+	Burned       string    `json:"burned,omitempty"`
+	Largest      *Transfer `json:"largest,omitempty"`
+}
+
+type tokenTotal struct {
+	Token  string `json:"token"`
+	Holder string `json:"holder"`
+	Amount string `json:"amount"`
+}
+
+type nftTotal struct {
+	Collection string `json:"collection"`
+	Holder     string `json:"holder"`
+	Sent       int    `json:"sent"`
+	Received   int    `json:"received"`
+}
+
+func newCheckpoint(from, to *big.Int, config Config) *checkpoint {
+	watchlist := make([]string, 0, len(config.Watchlist))
+	for _, address := range config.Watchlist {
+		watchlist = append(watchlist, NormalizeAddress(address))
+	}
+
+	exclude := make([]string, 0, len(config.Exclude))
+	for _, address := range config.Exclude {
+		exclude = append(exclude, NormalizeAddress(address))
+	}
+
+	between := make([]string, 0, len(config.Between))
+	for _, address := range config.Between {
+		between = append(between, NormalizeAddress(address))
+	}
+
+	cp := &checkpoint{
+		From:       from,
+		To:         to,
+		Network:    config.Network,
+		IncludeGas: config.IncludeGas,
+		Tokens:     config.Tokens,
+		Watchlist:  watchlist,
+		Exclude:    exclude,
+		Between:    between,
+		ZeroValue:  config.IncludeZero,
+		Trace:      config.Trace,
+		CountsOnly: config.TxCountsOnly,
+		TotalsOnly: config.CountOnly,
+		GasStats:   config.GasStats,
+		Buckets:    config.Histogram,
+	}
+
+	if config.Explain != "" {
+		cp.Explain = NormalizeAddress(config.Explain)
+	}
+
+	// The seed only matters when it picks the blocks
+	if config.Sample > 0 {
+		cp.Sample, cp.Seed = config.Sample, config.Seed
+	}
+
+	if config.TxMinWei != nil && config.TxMinWei.Sign() > 0 {
+		cp.TxMinWei = config.TxMinWei.String()
+	}
+
+	return cp
+}
+
+// Whether two checkpoints describe the same range scanned with the same settings
+func (cp *checkpoint) sameScan(other *checkpoint) bool {
+	if cp.From == nil || cp.To == nil || other.From == nil || other.To == nil {
+		return false
+	}
+
+	return cp.From.Cmp(other.From) == 0 &&
+		cp.To.Cmp(other.To) == 0 &&
+		cp.Network == other.Network &&
+		cp.IncludeGas == other.IncludeGas &&
+		cp.Tokens == other.Tokens &&
+		cp.TxMinWei == other.TxMinWei &&
+		cp.ZeroValue == other.ZeroValue &&
+		cp.Trace == other.Trace &&
+		cp.CountsOnly == other.CountsOnly &&
+		cp.TotalsOnly == other.TotalsOnly &&
+		cp.GasStats == other.GasStats &&
+		cp.Sample == other.Sample &&
+		cp.Explain == other.Explain &&
+		cp.Seed == other.Seed &&
+		sameBounds(cp.Buckets, other.Buckets) &&
+		// An empty list is left out of the file, so it reads back as nil
+		sameList(cp.Watchlist, other.Watchlist) &&
+		sameList(cp.Exclude, other.Exclude) &&
+		sameList(cp.Between, other.Between)
+}
+
+func sameList(a, b []string) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
+
+func sameBounds(a, b []*big.Int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Cmp(b[i]) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Load the checkpoint at path if it belongs to the same scan
+// A missing file just means there is nothing to resume, a mismatched one is an error so it isn't silently overwritten
+func loadCheckpoint(path string, from, to *big.Int, config Config) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	saved := &checkpoint{}
+	if err := json.Unmarshal(data, saved); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+
+	want := newCheckpoint(from, to, config)
+	if !saved.sameScan(want) {
+		return nil, fmt.Errorf("checkpoint %s is for a different scan (blocks %d to %d), remove it to start over", path, saved.From, saved.To)
+	}
+
+	return saved, nil
+}
+
+// Seed the aggregator with the totals of a previous run
+func (a *aggregator) restore(saved *checkpoint) error {
+	a.completed = append(a.completed, saved.Completed...)
+	a.stats.Transactions += saved.Transactions
+	a.stats.Transfers += saved.Transfers
+	a.stats.Largest = largerTransfer(a.stats.Largest, saved.Largest)
+	a.explained = append(a.explained, saved.Explained...)
+
+	if saved.Volume != "" {
+		volume, ok := new(big.Int).SetString(saved.Volume, 10)
+		if !ok {
+			return fmt.Errorf("checkpoint has an invalid volume %q", saved.Volume)
+		}
+		a.stats.Volume.Add(a.stats.Volume, volume)
+	}
+	if saved.Burned != "" {
+		burned, ok := new(big.Int).SetString(saved.Burned, 10)
+		if !ok {
+			return fmt.Errorf("checkpoint has an invalid burn total %q", saved.Burned)
+		}
+		a.stats.Burned.Add(a.stats.Burned, burned)
+	}
+
+	if a.addresses != nil {
+		for _, address := range saved.Addresses {
+			a.addresses[address] = struct{}{}
+		}
+	}
+
+	for address, amount := range saved.Balances {
+		change, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			return fmt.Errorf("checkpoint has an invalid balance for %s", address)
+		}
+		a.addBalance(address, change)
+	}
+
+	for address, amount := range saved.Sent {
+		sent, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			return fmt.Errorf("checkpoint has an invalid sent amount for %s", address)
+		}
+		flow := a.flow(address)
+		flow.Sent.Add(flow.Sent, sent)
+	}
+
+	for address, amount := range saved.Received {
+		received, ok := new(big.Int).SetString(amount, 10)
+		if !ok {
+			return fmt.Errorf("checkpoint has an invalid received amount for %s", address)
+		}
+		flow := a.flow(address)
+		flow.Received.Add(flow.Received, received)
+	}
+
+	for address, count := range saved.TxCounts {
+		a.flow(address).Transactions += count
+	}
+
+	if len(saved.Histogram) == len(a.histogram) {
+		for i, count := range saved.Histogram {
+			a.histogram[i] += count
+		}
+	}
+
+	if saved.GasPrices != nil {
+		a.gas.merge(*saved.GasPrices, 1)
+	}
+
+	for _, total := range saved.TokenTotal {
+		change, ok := new(big.Int).SetString(total.Amount, 10)
+		if !ok {
+			return fmt.Errorf("checkpoint has an invalid token balance for %s", total.Holder)
+		}
+		a.addToken(TokenHolder{Token: total.Token, Holder: total.Holder}, change)
+	}
+
+	for collection, transfers := range saved.NFTs {
+		a.nfts[collection] += transfers
+	}
+
+	for _, total := range saved.NFTHolders {
+		flow := a.nftFlow(TokenHolder{Token: total.Collection, Holder: total.Holder})
+		flow.Sent += total.Sent
+		flow.Received += total.Received
+	}
+
+	return nil
+}
+
+// Write the current totals to path, via a temp file so a crash mid write never corrupts the checkpoint
+func saveCheckpoint(path string, from, to *big.Int, config Config, a *aggregator) error {
+	cp := newCheckpoint(from, to, config)
+	cp.Completed = a.completed
+	cp.Transactions = a.stats.Transactions
+	cp.Transfers = a.stats.Transfers
+	cp.Volume = a.stats.Volume.String()
+	cp.Burned = a.stats.Burned.String()
+	cp.Largest = a.stats.Largest
+	cp.Explained = a.explained
+	cp.Balances = make(map[string]string, len(a.balances))
+
+	for address, balance := range a.balances {
+		cp.Balances[address] = balance.String()
+	}
+
+	cp.Sent = make(map[string]string, len(a.flows))
+	cp.Received = make(map[string]string, len(a.flows))
+	cp.TxCounts = make(map[string]int, len(a.flows))
+
+	for address, flow := range a.flows {
+		cp.Sent[address] = flow.Sent.String()
+		cp.Received[address] = flow.Received.String()
+		cp.TxCounts[address] = flow.Transactions
+	}
+
+	for address := range a.addresses {
+		cp.Addresses = append(cp.Addresses, address)
+	}
+
+	for holder, amount := range a.tokens {
+		cp.TokenTotal = append(cp.TokenTotal, tokenTotal{Token: holder.Token, Holder: holder.Holder, Amount: amount.String()})
+	}
+
+	cp.NFTs = a.nfts
+	for holder, flow := range a.nftHolders {
+		cp.NFTHolders = append(cp.NFTHolders, nftTotal{Collection: holder.Token, Holder: holder.Holder, Sent: flow.Sent, Received: flow.Received})
+	}
+
+	if config.GasStats {
+		cp.GasPrices = &a.gas
+	}
+	cp.Histogram = a.histogram
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".checkpoint-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResumedScanMatchesAFullOne(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.checkpoint")
+	full := scan(t, &fakeChain{}, 1, 20, Config{Workers: 1})
+
+	// Interrupt the first run after a few blocks, like Ctrl-C would
+	ctx, cancel := context.WithCancel(context.Background())
+	counted := 0
+	config := Config{Workers: 1, Checkpoint: path, OnBlock: func(*big.Int, map[string]*big.Int) {
+		if counted++; counted == 5 {
+			cancel()
+		}
+	}}
+	if _, err := Scan(ctx, &fakeChain{}, big.NewInt(1), big.NewInt(20), config); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("no checkpoint after the interruption: %v", err)
+	}
+
+	// The second run only fetches what the first one didn't count
+	chain := &fakeChain{}
+	config.OnBlock = nil
+	resumed := scan(t, chain, 1, 20, config)
+
+	// Requests already sent when the first run was cancelled still got counted, so at least five are done
+	if got := chain.blocks(); got > 15 || got == 0 {
+		t.Errorf("resumed run fetched %d blocks", got)
+	}
+	if !reflect.DeepEqual(resumed.Balances, full.Balances) {
+		t.Errorf("resumed balances %v, full scan %v", resumed.Balances, full.Balances)
+	}
+	if resumed.Stats.Transactions != full.Stats.Transactions || resumed.Flows[bob].Transactions != 20 {
+		t.Errorf("resumed stats %+v, full scan %+v", resumed.Stats, full.Stats)
+	}
+
+	// A finished scan has nothing left to resume
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("checkpoint still there after the scan finished: %v", err)
+	}
+}
+
+func TestCheckpointOfAnotherScanIsRefused(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.checkpoint")
+
+	// A failed block keeps the checkpoint around
+	scan(t, &fakeChain{failures: map[uint64]int{3: -1}}, 1, 5, Config{Workers: 1, Checkpoint: path})
+
+	if _, err := Scan(context.Background(), &fakeChain{}, big.NewInt(1), big.NewInt(6), Config{Workers: 1, Checkpoint: path}); err == nil {
+		t.Error("resumed the checkpoint of a different range")
+	}
+}
+
+func TestResumedScanRetriesFailedBlocks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.checkpoint")
+
+	first := scan(t, &fakeChain{failures: map[uint64]int{3: -1}}, 1, 5, Config{Workers: 1, Checkpoint: path})
+	if len(first.Failed) != 1 {
+		t.Fatalf("failed %v", first.Failed)
+	}
+
+	chain := &fakeChain{}
+	resumed := scan(t, chain, 1, 5, Config{Workers: 1, Checkpoint: path})
+	if chain.blocks() != 1 || chain.fetched(3) != 1 {
+		t.Errorf("resumed run fetched %d blocks", chain.blocks())
+	}
+	wantBalances(t, resumed, map[string]int64{alice: -15, bob: 15})
+}
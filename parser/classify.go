@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Address types reported by Classifier
+const (
+	EOA      = "EOA"
+	Contract = "Contract"
+)
+
+// Classifier tells contracts apart from externally owned accounts by whether the address has code
+// Results are cached, so asking for the same address twice costs no extra calls
+type Classifier struct {
+	s     *scanner
+	mu    sync.Mutex
+	types map[string]string
+}
+
+// NewClassifier creates a classifier whose calls share the retry and rate limit settings of config
+func NewClassifier(client Client, config Config) *Classifier {
+	return &Classifier{s: newScanner(client, config), types: map[string]string{}}
+}
+
+// Type returns EOA or Contract for address, or "" when its code could not be fetched
+// The code is read at the latest block, an address that deployed a contract since the scan shows as one
+func (c *Classifier) Type(ctx context.Context, address string) string {
+	address = NormalizeAddress(address)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if kind, ok := c.types[address]; ok {
+		return kind
+	}
+
+	var code string
+	err := c.s.call(ctx, func(ctx context.Context) error {
+		var err error
+		code, err = getCode(ctx, c.s.client, address)
+		return err
+	})
+	if err != nil {
+		c.s.log.Debug("cannot classify address", "address", address, "err", err)
+		return ""
+	}
+
+	kind := EOA
+	if strings.TrimPrefix(code, "0x") != "" {
+		kind = Contract
+	}
+	c.types[address] = kind
+
+	return kind
+}
+
+func getCode(ctx context.Context, client Client, address string) (string, error) {
+	r, err := client.Call(ctx, "eth_getCode", address, "latest")
+	if err != nil {
+		return "", err
+	}
+
+	if r.Error != nil {
+		return "", r.Error
+	}
+
+	return r.GetString()
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// Alice is a contract, bob a wallet, and carol's code cannot be read
+type codeChain struct {
+	calls atomic.Int32
+}
+
+func (c *codeChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	if method != "eth_getCode" {
+		return nil, fmt.Errorf("unexpected %s call", method)
+	}
+	c.calls.Add(1)
+
+	switch params[0].(string) {
+	case alice:
+		return &jsonrpc.RPCResponse{Result: "0x6080604052"}, nil
+	case bob:
+		return &jsonrpc.RPCResponse{Result: "0x"}, nil
+	}
+	return &jsonrpc.RPCResponse{Error: &jsonrpc.RPCError{Code: -32602, Message: "invalid address"}}, nil
+}
+
+func TestClassifierTellsContractsFromWallets(t *testing.T) {
+	chain := &codeChain{}
+	classifier := NewClassifier(chain, Config{Workers: 1})
+
+	for address, want := range map[string]string{alice: Contract, bob: EOA, carol: ""} {
+		if got := classifier.Type(context.Background(), address); got != want {
+			t.Errorf("%s is %q, want %q", address, got, want)
+		}
+	}
+
+	// Known addresses come from the cache whatever their casing, carol failed and is asked again
+	calls := chain.calls.Load()
+	classifier.Type(context.Background(), alice[:2]+strings.ToUpper(alice[2:]))
+	classifier.Type(context.Background(), bob)
+	if got := chain.calls.Load(); got != calls {
+		t.Errorf("%d more calls for cached addresses", got-calls)
+	}
+	classifier.Type(context.Background(), carol)
+	if got := chain.calls.Load(); got == calls {
+		t.Error("a failed lookup was cached")
+	}
+}
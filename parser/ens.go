@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// The ENS registry lives at the same address on mainnet and the main testnets
+const ensRegistry = "0x00000000000c2e074ec69a0dfb2997ba6c7d2e1e"
+
+// Function selectors used for reverse resolution
+const (
+	resolverSelector = "0x0178b8bf"
+	nameSelector     = "0x691f3431"
+	addrSelector     = "0x3b3b57de"
+)
+
+const zeroAddress = "0x0000000000000000000000000000000000000000"
+
+// Resolver looks up the primary ENS name of addresses
+// Lookups are cached, so asking for the same address twice costs no extra calls
+type Resolver struct {
+	s     *scanner
+	mu    sync.Mutex
+	names map[string]string
+}
+
+// NewResolver creates a resolver whose calls share the retry and rate limit settings of config
+func NewResolver(client Client, config Config) *Resolver {
+	return &Resolver{s: newScanner(client, config), names: map[string]string{}}
+}
+
+// Name returns the primary ENS name of address, or "" when it has none
+// Reverse records can claim any name, so the name only counts when it resolves back to the same address
+func (r *Resolver) Name(ctx context.Context, address string) string {
+	address = NormalizeAddress(address)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if name, ok := r.names[address]; ok {
+		return name
+	}
+
+	name := r.reverse(ctx, address)
+
+	// A cancelled lookup says nothing about the address, so don't remember it
+	if ctx.Err() == nil {
+		r.names[address] = name
+	}
+
+	return name
+}
+
+func (r *Resolver) reverse(ctx context.Context, address string) string {
+	node := namehash(strings.TrimPrefix(address, "0x") + ".addr.reverse")
+
+	resolver, ok := r.resolver(ctx, node)
+	if !ok {
+		return ""
+	}
+
+	data, err := r.s.ethCall(ctx, resolver, nameSelector+node)
+	if err != nil {
+		return ""
+	}
+
+	name, err := decodeString(data)
+	if err != nil || name == "" {
+		return ""
+	}
+
+	// Check the forward record
+	forward := namehash(name)
+
+	resolver, ok = r.resolver(ctx, forward)
+	if !ok {
+		return ""
+	}
+
+	data, err = r.s.ethCall(ctx, resolver, addrSelector+forward)
+	if err != nil || len(data) < 32 || wordToAddress(hex.EncodeToString(data[:32])) != address {
+		return ""
+	}
+
+	return name
+}
+
+// Ask the registry for the resolver contract of node, false when none is set
+func (r *Resolver) resolver(ctx context.Context, node string) (string, bool) {
+	data, err := r.s.ethCall(ctx, ensRegistry, resolverSelector+node)
+	if err != nil || len(data) < 32 {
+		return "", false
+	}
+
+	resolver := wordToAddress(hex.EncodeToString(data[:32]))
+
+	return resolver, resolver != zeroAddress
+}
+
+// EIP-137 namehash of a dotted name as 64 hex characters without the 0x prefix
+func namehash(name string) string {
+	node := make([]byte, 32)
+
+	if name != "" {
+		labels := strings.Split(name, ".")
+		for i := len(labels) - 1; i >= 0; i-- {
+			node = keccak256(node, keccak256([]byte(labels[i])))
+		}
+	}
+
+	return hex.EncodeToString(node)
+}
+
+func keccak256(data ...[]byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hash.Write(d)
+	}
+
+	return hash.Sum(nil)
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+const ensResolver = "0x00000000000000000000000000000000000000e5"
+
+// The registry and a single resolver that give alice the name alice.eth
+// Carol's reverse record claims alice.eth too, but that name points back at alice
+type ensChain struct {
+	calls atomic.Int32
+}
+
+func abiString(s string) string {
+	data := hex.EncodeToString([]byte(s))
+	if pad := len(data) % 64; pad > 0 {
+		data += strings.Repeat("0", 64-pad)
+	}
+	return word("20") + word(fmt.Sprintf("%x", len(s))) + data
+}
+
+func (c *ensChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	if method != "eth_call" {
+		return nil, fmt.Errorf("unexpected %s call", method)
+	}
+	c.calls.Add(1)
+
+	call := params[0].(map[string]string)
+	to, data := call["to"], call["data"]
+	selector, node := data[:10], data[10:]
+
+	reverse := map[string]string{
+		namehash(strings.TrimPrefix(alice, "0x") + ".addr.reverse"): "alice.eth",
+		namehash(strings.TrimPrefix(carol, "0x") + ".addr.reverse"): "alice.eth",
+	}
+	forward := map[string]string{namehash("alice.eth"): alice}
+
+	switch {
+	case to == ensRegistry && selector == resolverSelector:
+		if reverse[node] != "" || forward[node] != "" {
+			return &jsonrpc.RPCResponse{Result: "0x" + word(ensResolver)}, nil
+		}
+		return &jsonrpc.RPCResponse{Result: "0x" + word("0")}, nil
+	case to == ensResolver && selector == nameSelector:
+		return &jsonrpc.RPCResponse{Result: "0x" + abiString(reverse[node])}, nil
+	case to == ensResolver && selector == addrSelector:
+		return &jsonrpc.RPCResponse{Result: "0x" + word(forward[node])}, nil
+	}
+	return &jsonrpc.RPCResponse{Error: &jsonrpc.RPCError{Code: -32000, Message: "execution reverted"}}, nil
+}
+
+func TestNamehash(t *testing.T) {
+	if got := namehash(""); got != strings.Repeat("0", 64) {
+		t.Errorf("namehash of the root is %s", got)
+	}
+	if got := namehash("eth"); got != "93cdeb708b7545dc668eb9280176169d1c33cfd8ed6f04690a0bcc88a93fc4ae" {
+		t.Errorf("namehash(eth) = %s", got)
+	}
+}
+
+func TestResolverName(t *testing.T) {
+	chain := &ensChain{}
+	resolver := NewResolver(chain, Config{Workers: 1})
+	ctx := context.Background()
+
+	for address, want := range map[string]string{
+		alice: "alice.eth",
+		bob:   "",
+		// Claims a name that doesn't resolve back to it
+		carol: "",
+	} {
+		if got := resolver.Name(ctx, address); got != want {
+			t.Errorf("%s is named %q, want %q", address, got, want)
+		}
+	}
+
+	// Every answer is cached, empty ones too
+	calls := chain.calls.Load()
+	resolver.Name(ctx, alice)
+	resolver.Name(ctx, bob)
+	if got := chain.calls.Load(); got != calls {
+		t.Errorf("asking again made %d more calls", got-calls)
+	}
+}
@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"math/big"
+	"sort"
+)
+
+// Contribution is one transaction's share of the total of the address given as Config.Explain
+// A transaction can contribute more than once, for example its value and its gas fee
+type Contribution struct {
+	Block  *big.Int `json:"block"`
+	Index  int      `json:"index"`
+	Hash   string   `json:"hash"`
+	Amount *big.Int `json:"amount"`
+}
+
+// The changes among those of one transaction that belong to address
+func contributions(address string, block *big.Int, index int, hash string, changes []BalanceChange) []Contribution {
+	found := []Contribution{}
+
+	for i := range changes {
+		if changes[i].Address == address {
+			found = append(found, Contribution{Block: block, Index: index, Hash: hash, Amount: new(big.Int).Set(&changes[i].Balance)})
+		}
+	}
+
+	return found
+}
+
+// Put contributions in chain order, they arrive in whatever order the workers finished their blocks
+func sortContributions(explained []Contribution) {
+	sort.SliceStable(explained, func(i, j int) bool {
+		if c := explained[i].Block.Cmp(explained[j].Block); c != 0 {
+			return c < 0
+		}
+		return explained[i].Index < explained[j].Index
+	})
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestExplainedContributionsAddUpToTheTotal(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 9, gasUsed: 2, gasPrice: 3}, {from: carol, to: bob, value: 4}},
+		2: {{from: bob, to: alice, value: 11, gasUsed: 1, gasPrice: 1}},
+		3: {{from: carol, to: carol, value: 1}},
+		4: {{from: alice, to: alice, value: 5, gasUsed: 1, gasPrice: 2}},
+	}}
+
+	for _, address := range []string{alice, bob, carol} {
+		result := scan(t, chain, 1, 4, Config{Workers: 3, IncludeGas: true, Explain: address})
+
+		sum := new(big.Int)
+		for i, contribution := range result.Explained {
+			sum.Add(sum, contribution.Amount)
+
+			// In chain order, however the workers finished
+			if i > 0 {
+				previous := result.Explained[i-1]
+				if c := previous.Block.Cmp(contribution.Block); c > 0 || c == 0 && previous.Index > contribution.Index {
+					t.Errorf("%s: %v comes after %v", address, contribution, previous)
+				}
+			}
+		}
+		if sum.Cmp(result.Balances[address]) != 0 {
+			t.Errorf("%s: contributions add up to %v, the total is %v", address, sum, result.Balances[address])
+		}
+	}
+}
+
+func TestExplainKeepsEveryChangeOfATransaction(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 9, gasUsed: 2, gasPrice: 3}},
+	}}
+
+	// Alice sent the value and paid the fee, two changes from one transaction
+	result := scan(t, chain, 1, 1, Config{Workers: 1, IncludeGas: true, Explain: alice})
+	if len(result.Explained) != 2 {
+		t.Fatalf("got %v, want the value and the fee", result.Explained)
+	}
+	for _, contribution := range result.Explained {
+		if contribution.Hash != txHash(1, 0) || contribution.Block.Int64() != 1 {
+			t.Errorf("got %+v", contribution)
+		}
+	}
+
+	if result := scan(t, chain, 1, 1, Config{Workers: 1, IncludeGas: true}); len(result.Explained) != 0 {
+		t.Errorf("explained %v without Explain", result.Explained)
+	}
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"math/big"
+
+	"github.com/ofen/getblock-go/eth"
+)
+
+// GasPrices are the prices offered by the transactions of a scan, in wei per gas, in no particular order
+// Only filled in when Config.GasStats is set
+type GasPrices struct {
+	// Price has one entry per transaction, for EIP-1559 transactions it is the effective price the node reports
+	Price []*big.Int `json:"price,omitempty"`
+
+	// MaxFee and MaxPriorityFee only have entries for EIP-1559 transactions
+	MaxFee         []*big.Int `json:"max_fee,omitempty"`
+	MaxPriorityFee []*big.Int `json:"max_priority_fee,omitempty"`
+}
+
+func (g *GasPrices) add(tx eth.Transaction) {
+	if tx.GasPrice != nil {
+		g.Price = append(g.Price, tx.GasPrice)
+	}
+	if tx.MaxFeePerGas != nil {
+		g.MaxFee = append(g.MaxFee, tx.MaxFeePerGas)
+	}
+	if tx.MaxPriorityFeePerGas != nil {
+		g.MaxPriorityFee = append(g.MaxPriorityFee, tx.MaxPriorityFeePerGas)
+	}
+}
+
+// Add the prices of other, or take them back out again when sign is negative
+func (g *GasPrices) merge(other GasPrices, sign int) {
+	if sign > 0 {
+		g.Price = append(g.Price, other.Price...)
+		g.MaxFee = append(g.MaxFee, other.MaxFee...)
+		g.MaxPriorityFee = append(g.MaxPriorityFee, other.MaxPriorityFee...)
+		return
+	}
+
+	g.Price = withoutValues(g.Price, other.Price)
+	g.MaxFee = withoutValues(g.MaxFee, other.MaxFee)
+	g.MaxPriorityFee = withoutValues(g.MaxPriorityFee, other.MaxPriorityFee)
+}
+
+// Drop one occurrence of every value in removed, equal prices are interchangeable so any one will do
+func withoutValues(values, removed []*big.Int) []*big.Int {
+	if len(removed) == 0 {
+		return values
+	}
+
+	counts := make(map[string]int, len(removed))
+	for _, value := range removed {
+		counts[value.String()]++
+	}
+
+	kept := values[:0]
+	for _, value := range values {
+		if key := value.String(); counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+		kept = append(kept, value)
+	}
+
+	return kept
+}
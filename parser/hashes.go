@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/ofen/getblock-go/eth"
+)
+
+var blockHashPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+
+// ValidBlockHash reports whether hash looks like a block hash, 0x and 64 hex digits
+func ValidBlockHash(hash string) bool {
+	return blockHashPattern.MatchString(hash)
+}
+
+// ScanHashes is Scan for a set of blocks picked by hash rather than a range of numbers
+// The blocks are fetched with eth_getBlockByHash, so a block that was reorged away is still counted as it was
+// Checkpoints, the block cache, batching and Config.Seen only work on ranges and are not used
+func ScanHashes(ctx context.Context, client Client, hashes []string, config Config) (*Result, error) {
+	if config.Workers < 1 {
+		return nil, fmt.Errorf("workers must be at least 1, got %d", config.Workers)
+	}
+
+	for _, hash := range hashes {
+		if !ValidBlockHash(hash) {
+			return nil, fmt.Errorf("invalid block hash %q", hash)
+		}
+	}
+
+	totals := newAggregator(config)
+	input := make(chan string, config.Workers)
+	output := make(chan blockResult, config.Workers)
+
+	var wg sync.WaitGroup
+	s := newScanner(client, config)
+	s.startReceipts()
+
+	for i := 0; i < config.Workers; i++ {
+		wg.Add(1)
+		go s.parseHashes(ctx, &wg, input, output)
+	}
+
+	go func() {
+		defer close(input)
+
+		for _, hash := range hashes {
+			select {
+			case input <- hash:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go s.closeWhenDone(&wg, output)
+
+	done := 0
+	start := time.Now()
+
+	for result := range output {
+		done++
+		if result.err != nil && stoppedBy(ctx, result.err) {
+			s.log.Debug("block cut short by the end of the scan", "hash", result.hash)
+		} else {
+			totals.add(result)
+			config.Metrics.block(result.err != nil)
+			if config.OnBlock != nil && result.err == nil {
+				config.OnBlock(result.block, totals.netChanges(result))
+			}
+		}
+
+		if config.Progress != nil {
+			config.Progress(done, len(hashes))
+		}
+	}
+
+	s.log.Info("scan finished", "blocks", done, "failed", len(totals.failedHashes), "duration", time.Since(start).Round(time.Millisecond))
+
+	return s.result(ctx, totals, len(hashes)), ctx.Err()
+}
+
+func (s *scanner) parseHashes(ctx context.Context, wg *sync.WaitGroup, input chan string, output chan blockResult) {
+	defer wg.Done()
+
+	for hash := range input {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A failed block only has the hash it was asked for, the aggregator keys it by that
+		var result blockResult
+		block, err := s.fetchBlockByHash(ctx, hash)
+		if err == nil {
+			result = s.parseBlock(ctx, block.Number, block)
+		} else {
+			result = blockResult{hash: hash, err: err}
+		}
+
+		if err := result.err; err != nil && !stoppedBy(ctx, err) {
+			s.log.Error("cannot fetch block", "hash", hash, "err", err)
+		}
+
+		output <- result
+	}
+}
+
+// Fetch a single block by hash over RPC, retrying transient failures
+func (s *scanner) fetchBlockByHash(ctx context.Context, hash string) (*eth.Block, error) {
+	var raw []byte
+
+	err := s.call(ctx, func(ctx context.Context) error {
+		r, err := s.client.Call(ctx, "eth_getBlockByHash", hash, true)
+		if err != nil {
+			return err
+		}
+		if r.Error != nil {
+			return r.Error
+		}
+
+		// Unknown hashes come back as null, same as blocks past the head
+		if r.Result == nil {
+			return fmt.Errorf("block %s not found", hash)
+		}
+
+		raw, err = json.Marshal(r.Result)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeBlock(raw)
+}
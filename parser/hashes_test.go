@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// Serves the blocks of the fake chain by their hash, unknown hashes get null like a real node
+type hashChain struct {
+	fakeChain
+	byNumber atomic.Int32
+}
+
+func (c *hashChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	switch method {
+	case "eth_getBlockByNumber":
+		c.byNumber.Add(1)
+	case "eth_getBlockByHash":
+		hash := params[0].(string)
+		n, err := strconv.ParseUint(hash[2:], 16, 64)
+		if err != nil || n == 0 || n > 100 {
+			return &jsonrpc.RPCResponse{}, nil
+		}
+		return c.fakeChain.Call(ctx, "eth_getBlockByNumber", fmt.Sprintf("%#x", n), true)
+	}
+	return c.fakeChain.Call(ctx, method, params...)
+}
+
+func blockHash(n uint64) string {
+	return fmt.Sprintf("0x%064x", n)
+}
+
+func TestScanHashesFetchesBlocksByHash(t *testing.T) {
+	chain := &hashChain{}
+	unknown := blockHash(1000)
+
+	result, err := ScanHashes(context.Background(), chain, []string{blockHash(2), blockHash(5), unknown}, Config{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantBalances(t, result, map[string]int64{alice: -7, bob: 7})
+	if len(result.FailedHashes) != 1 || result.FailedHashes[0] != unknown {
+		t.Errorf("failed hashes %v, want only the unknown one", result.FailedHashes)
+	}
+	if got := chain.byNumber.Load(); got != 0 {
+		t.Errorf("asked for %d blocks by number", got)
+	}
+}
+
+func TestScanHashesValidatesFirst(t *testing.T) {
+	for _, hash := range []string{"0x1234", blockHash(2)[2:], "0x" + strings.Repeat("g", 64)} {
+		chain := &hashChain{}
+		if _, err := ScanHashes(context.Background(), chain, []string{blockHash(2), hash}, Config{Workers: 1}); err == nil {
+			t.Errorf("%q accepted", hash)
+		}
+		if chain.blocks() != 0 {
+			t.Errorf("fetched blocks before rejecting %q", hash)
+		}
+	}
+}
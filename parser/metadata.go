@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Function selectors of the optional ERC-20 metadata getters
+const (
+	decimalsSelector = "0x313ce567"
+	symbolSelector   = "0x95d89b41"
+)
+
+// TokenInfo is the display metadata of an ERC-20 contract
+// Decimals is -1 and Symbol is empty when the contract doesn't expose them
+type TokenInfo struct {
+	Symbol   string
+	Decimals int
+}
+
+// Token metadata never changes, so look each contract up at most once per scan
+type tokenCache struct {
+	mu    sync.Mutex
+	infos map[string]TokenInfo
+}
+
+func (s *scanner) tokenInfo(ctx context.Context, token string) TokenInfo {
+	s.tokens.mu.Lock()
+	defer s.tokens.mu.Unlock()
+
+	if info, ok := s.tokens.infos[token]; ok {
+		return info
+	}
+
+	info := TokenInfo{Decimals: -1}
+
+	// Both getters are optional in the standard, so a failure just means we show raw amounts
+	if data, err := s.ethCall(ctx, token, decimalsSelector); err == nil {
+		if decimals, ok := decodeUint8(data); ok {
+			info.Decimals = decimals
+		}
+	}
+
+	if data, err := s.ethCall(ctx, token, symbolSelector); err == nil {
+		info.Symbol, _ = decodeString(data)
+	}
+
+	if s.tokens.infos == nil {
+		s.tokens.infos = map[string]TokenInfo{}
+	}
+	s.tokens.infos[token] = info
+
+	return info
+}
+
+// Call a read only contract function at the latest block and return the raw return data
+func (s *scanner) ethCall(ctx context.Context, to string, data string) ([]byte, error) {
+	var result []byte
+
+	err := s.call(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = ethCall(ctx, s.client, to, data)
+		return err
+	})
+
+	return result, err
+}
+
+func ethCall(ctx context.Context, client Client, to string, data string) ([]byte, error) {
+	r, err := client.Call(ctx, "eth_call", map[string]string{"to": to, "data": data}, "latest")
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	encoded, err := r.GetString()
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+}
+
+// decimals() returns a uint8 padded to a full word
+func decodeUint8(data []byte) (int, bool) {
+	if len(data) < 32 {
+		return 0, false
+	}
+
+	value := new(big.Int).SetBytes(data[:32])
+	if !value.IsInt64() || value.Int64() > 255 {
+		return 0, false
+	}
+
+	return int(value.Int64()), true
+}
+
+// symbol() is usually an ABI encoded dynamic string, but some older tokens return a bytes32 instead
+func decodeString(data []byte) (string, error) {
+	var raw []byte
+
+	switch {
+	case len(data) >= 64 && new(big.Int).SetBytes(data[:32]).Cmp(big.NewInt(32)) == 0:
+		length := new(big.Int).SetBytes(data[32:64])
+		if !length.IsInt64() || length.Int64() > int64(len(data)-64) {
+			return "", fmt.Errorf("string length out of range")
+		}
+		raw = data[64 : 64+length.Int64()]
+	case len(data) == 32:
+		raw = []byte(strings.TrimRight(string(data), "\x00"))
+	default:
+		return "", fmt.Errorf("unexpected string encoding of %d bytes", len(data))
+	}
+
+	if !utf8.Valid(raw) {
+		return "", fmt.Errorf("string is not valid utf-8")
+	}
+
+	return string(raw), nil
+}
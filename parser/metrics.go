@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics are the Prometheus collectors a scan reports to
+// A nil *Metrics is valid and records nothing, so the scan code never has to check
+type Metrics struct {
+	blocksProcessed prometheus.Counter
+	blocksFailed    prometheus.Counter
+	retries         prometheus.Counter
+	rpcLatency      prometheus.Histogram
+}
+
+// NewMetrics creates the scan collectors and registers them with reg
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		blocksProcessed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "blockparser_blocks_processed_total",
+			Help: "Blocks fetched and aggregated successfully.",
+		}),
+		blocksFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "blockparser_blocks_failed_total",
+			Help: "Blocks given up on after all retries.",
+		}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "blockparser_rpc_retries_total",
+			Help: "RPC calls retried after a failed attempt.",
+		}),
+		rpcLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "blockparser_rpc_duration_seconds",
+			Help:    "Latency of single RPC attempts.",
+			Buckets: prometheus.DefBuckets,
+		}),
+	}
+
+	reg.MustRegister(m.blocksProcessed, m.blocksFailed, m.retries, m.rpcLatency)
+
+	return m
+}
+
+func (m *Metrics) block(failed bool) {
+	if m == nil {
+		return
+	}
+
+	if failed {
+		m.blocksFailed.Inc()
+	} else {
+		m.blocksProcessed.Inc()
+	}
+}
+
+func (m *Metrics) retry() {
+	if m != nil {
+		m.retries.Inc()
+	}
+}
+
+func (m *Metrics) rpc(took time.Duration) {
+	if m != nil {
+		m.rpcLatency.Observe(took.Seconds())
+	}
+}
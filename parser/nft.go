@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"math/big"
+	"strings"
+)
+
+// NFTTransfer is a single ERC-721 token moving from one holder to another
+type NFTTransfer struct {
+	Collection string
+	From       string
+	To         string
+	TokenID    *big.Int
+}
+
+// NFTFlow counts the ERC-721 transfers of one holder in one collection
+type NFTFlow struct {
+	Sent     int
+	Received int
+}
+
+// Decode an ERC-721 Transfer log
+// The event has the same signature as ERC-20, but the token id is indexed as well, so from, to and the id
+// are all topics and data is empty
+func decodeNFTTransfer(log Log) (NFTTransfer, bool) {
+	if len(log.Topics) != 4 || !strings.EqualFold(log.Topics[0], transferTopic) || strings.TrimPrefix(log.Data, "0x") != "" {
+		return NFTTransfer{}, false
+	}
+
+	id, ok := new(big.Int).SetString(strings.TrimPrefix(log.Topics[3], "0x"), 16)
+	if !ok {
+		return NFTTransfer{}, false
+	}
+
+	return NFTTransfer{
+		Collection: NormalizeAddress(log.Address),
+		From:       wordToAddress(log.Topics[1]),
+		To:         wordToAddress(log.Topics[2]),
+		TokenID:    id,
+	}, true
+}
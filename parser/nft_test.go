@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const punks = "0xb47e3cd837ddf8e4c57f05d70ab865de6e193bbb"
+
+func TestDecodeNFTTransfer(t *testing.T) {
+	// Token ids are full 256 bit words, this one does not fit in a uint64
+	id := "1" + strings.Repeat("0", 20)
+	log := Log{
+		Address: strings.ToUpper(punks[:2]) + punks[2:],
+		Topics:  []string{transferTopic, "0x" + word(alice), "0x" + word(bob), "0x" + word(id)},
+		Data:    "0x",
+	}
+
+	nft, ok := decodeNFTTransfer(log)
+	if !ok {
+		t.Fatal("not decoded")
+	}
+	if nft.Collection != punks || nft.From != alice || nft.To != bob || nft.TokenID.Text(16) != id {
+		t.Errorf("got %+v", nft)
+	}
+
+	// Not an amount, the same log is no ERC-20 transfer
+	if changes, ok := decodeTransfer(log); ok {
+		t.Errorf("decoded as ERC-20 %v", changes)
+	}
+}
+
+func TestDecodeNFTTransferIgnoresERC20(t *testing.T) {
+	for name, log := range map[string]Log{
+		"erc-20":    {Address: usdc, Topics: []string{transferTopic, "0x" + word(alice), "0x" + word(bob)}, Data: "0x" + word("7")},
+		"with data": {Address: punks, Topics: []string{transferTopic, "0x" + word(alice), "0x" + word(bob), "0x" + word("7")}, Data: "0x" + word("1")},
+		"bad id":    {Address: punks, Topics: []string{transferTopic, "0x" + word(alice), "0x" + word(bob), "0xzz"}},
+	} {
+		if nft, ok := decodeNFTTransfer(log); ok {
+			t.Errorf("%s decoded as %+v", name, nft)
+		}
+	}
+}
+
+func TestNFTTransfersAreCountedPerCollectionAndHolder(t *testing.T) {
+	a := newAggregator(Config{})
+	a.add(blockResult{nfts: []NFTTransfer{
+		{Collection: punks, From: alice, To: bob},
+		{Collection: punks, From: bob, To: carol},
+		{Collection: usdc, From: alice, To: carol},
+	}})
+
+	if a.nfts[punks] != 2 || a.nfts[usdc] != 1 {
+		t.Errorf("collections %v", a.nfts)
+	}
+	for holder, want := range map[TokenHolder]NFTFlow{
+		{Token: punks, Holder: alice}: {Sent: 1},
+		{Token: punks, Holder: bob}:   {Sent: 1, Received: 1},
+		{Token: punks, Holder: carol}: {Received: 1},
+		{Token: usdc, Holder: carol}:  {Received: 1},
+	} {
+		if got := a.nftHolders[holder]; got == nil || *got != want {
+			t.Errorf("%v: %+v, want %+v", holder, got, want)
+		}
+	}
+}
@@ -0,0 +1,876 @@
+// Package parser scans a range of Ethereum blocks and aggregates the net
+// balance change of every address that sent or received ETH.
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ofen/getblock-go/eth"
+	"golang.org/x/time/rate"
+)
+
+// BalanceChange is a single signed change to an address balance
+type BalanceChange struct {
+	Address string
+	Balance big.Int
+}
+
+// Config controls how a scan is run
+type Config struct {
+	// Workers is the number of blocks fetched concurrently
+	// Performance is limited by the network speed more than the CPU
+	Workers int
+
+	// BatchSize is how many blocks a worker requests in one JSON-RPC batch, cutting round trips on slow endpoints
+	// Zero or one fetches every block on its own, as do clients that can't send batches
+	BatchSize int
+
+	// IncludeGas deducts gas fees from the sender
+	// This costs one extra RPC call per transaction
+	IncludeGas bool
+
+	// ReceiptWorkers is the number of receipts fetched concurrently, shared by all block workers
+	// Only used when IncludeGas or Tokens needs receipts, zero means the same as Workers
+	ReceiptWorkers int
+
+	// RPCTimeout bounds every single RPC call so one slow request can't stall a worker
+	// Zero means calls are only bounded by the scan context
+	RPCTimeout time.Duration
+
+	// Retries is how many times a failed RPC call is retried before the block is given up on
+	Retries int
+
+	// RetryDelay is the base delay before the first retry, it doubles on every further attempt
+	RetryDelay time.Duration
+
+	// RateLimitDelay is the base delay used instead of RetryDelay when the endpoint throttles us
+	// A Retry-After hint from the server takes precedence when it is longer
+	RateLimitDelay time.Duration
+
+	// BreakerThreshold is the number of failed calls in a row after which the scan stops calling the endpoint
+	// for BreakerCooldown, then tries a single call before resuming, zero never stops
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// RPS caps the requests per second across all workers, zero means unlimited
+	RPS float64
+
+	// Tokens decodes ERC-20 Transfer events from receipts into Result.Tokens, and ERC-721 ones into Result.NFTs
+	// Like IncludeGas this costs one receipt call per transaction, the two share that call
+	Tokens bool
+
+	// Trace folds ETH moved by internal contract calls into the balances using debug_traceBlockByNumber
+	// Costs one extra call per block, endpoints without the debug namespace fall back to plain values with a warning
+	Trace bool
+
+	// TxCountsOnly tells the scan that only Blocks, Headers and Stats.Transactions will be read
+	// Blocks are then fetched with transaction hashes instead of full transactions, which is a fraction of the payload
+	// Any setting that looks at transaction values or receipts still needs the full transactions, so it brings them back
+	TxCountsOnly bool
+
+	// CountOnly keeps no per-address totals, only Stats and the number of unique addresses in Result.Addresses
+	// Balances and Flows stay empty, so huge ranges need memory for a set of addresses rather than their totals
+	CountOnly bool
+
+	// IncludeZero records the sender and receiver of zero value transactions with a zero change
+	// so addresses that only call contracts still show up in Balances
+	IncludeZero bool
+
+	// TxMinWei skips every transaction whose value is below it, including its gas and token transfers
+	// The filter applies per transaction before aggregation, unlike a threshold on the net totals
+	// Nil keeps every transaction
+	TxMinWei *big.Int
+
+	// Watchlist restricts the result to these addresses, matched case-insensitively, token and NFT holders included
+	// Watched addresses without any activity are reported with a zero change
+	// Leave it empty to keep every address
+	Watchlist []string
+
+	// Between restricts the scan to transactions between these two addresses, in either direction
+	// Leave it empty to keep every transaction
+	Between []string
+
+	// Exclude drops these addresses from the result, matched case-insensitively
+	// Only their own totals go, what they sent to or received from other addresses still counts for those
+	Exclude []string
+
+	// Explain keeps every transaction that changed this address's total in Result.Explained, an audit trail of the total
+	// Leave it empty to record nothing
+	Explain string
+
+	// Sample scans only this many blocks of the range, picked at random without repeats, zero scans every block
+	// Seed makes the pick reproducible, the same range, Sample and Seed always select the same blocks
+	Sample int
+	Seed   int64
+
+	// GasStats collects the gas price of every transaction into Result.Gas
+	// Memory grows with the number of transactions rather than addresses, so it is off by default
+	GasStats bool
+
+	// Histogram are the ascending upper bounds in wei of the buckets Result.Histogram counts transfers into
+	// A value equal to a bound goes into the next bucket up, nil skips the histogram
+	Histogram []*big.Int
+
+	// CacheDir keeps fetched blocks on disk so overlapping scans don't download them again
+	// Leave it empty to disable the cache
+	CacheDir string
+
+	// Network names the chain the client talks to, block numbers alone don't tell two chains apart
+	// Cached blocks go into a subdirectory of this name and a checkpoint is only resumed on the same network
+	Network string
+
+	// CacheFinalized is the highest block number considered safe from reorgs
+	// Only blocks at or below it are written to the cache, nil caches nothing
+	CacheFinalized *big.Int
+
+	// Checkpoint is a file the scan state is saved to, so an interrupted scan can be resumed
+	// Rerunning the same range with the same settings skips the blocks that already completed
+	// The file is removed once a scan finishes without failures
+	Checkpoint string
+
+	// CheckpointInterval is how often the checkpoint is written while scanning
+	CheckpointInterval time.Duration
+
+	// Seen is shared by scans whose ranges may overlap, like a backfill and the blocks that follow it
+	// Blocks already in it are not fetched, and a block is only counted by whichever scan adds it first
+	Seen *BlockSet
+
+	// Logger receives diagnostics like failed blocks, retries and timing, nil uses slog.Default()
+	Logger *slog.Logger
+
+	// Metrics receives block and RPC counters while scanning, nil disables them
+	Metrics *Metrics
+
+	// Progress is called from the aggregation loop after each block, failed or not
+	Progress func(done, total int)
+
+	// OnBlock is called from the aggregation loop for every block that goes into the totals
+	// It gets the net change of each kept address in that block, the map is the callee's to keep
+	OnBlock func(block *big.Int, changes map[string]*big.Int)
+}
+
+// Result is the outcome of a scan
+type Result struct {
+	// Balances maps each address to its net balance change in wei
+	Balances map[string]*big.Int
+
+	// Addresses is the number of unique addresses, the length of Balances unless Config.CountOnly left it empty
+	Addresses int
+
+	// Flows holds the gross amounts behind each net change in Balances, keyed the same way
+	Flows map[string]*Flow
+
+	// Failed lists the blocks that could not be fetched after all retries, in ascending order
+	// Their transactions are missing from Balances
+	Failed []*big.Int
+
+	// FailedHashes lists the blocks of ScanHashes that could not be fetched, their number is unknown
+	FailedHashes []string
+
+	// Blocks is the number of blocks in the scanned range, or in the sample when Config.Sample picked fewer
+	Blocks int
+
+	// Sampled is set when only Blocks random blocks of the range were scanned
+	Sampled bool
+
+	// Pending is the number of tentative transactions from the pending block included in the totals
+	Pending int
+
+	// From and To are the range Scan was asked for, both nil for ScanHashes
+	From, To *big.Int
+
+	// Tokens maps each token contract and holder to the net change in the token's smallest unit
+	// Only filled in when Config.Tokens is set
+	Tokens map[TokenHolder]*big.Int
+
+	// TokenInfo holds the symbol and decimals of every contract in Tokens and NFTs
+	TokenInfo map[string]TokenInfo
+
+	// NFTs counts the ERC-721 transfers of each collection, NFTHolders splits them up by holder
+	// Only filled in when Config.Tokens is set
+	NFTs       map[string]int
+	NFTHolders map[TokenHolder]*NFTFlow
+
+	// Stats covers every transaction in the scanned blocks, the watchlist does not apply
+	Stats Stats
+
+	// Gas holds the gas prices of the same transactions as Stats, only with Config.GasStats
+	Gas GasPrices
+
+	// Histogram counts the value transfers per bucket of Config.Histogram, with one more bucket for
+	// everything from the last bound upwards
+	Histogram []int
+
+	// Headers identifies the blocks this scan fetched itself, in ascending order
+	// Blocks resumed from a checkpoint are not included
+	Headers []Header
+
+	// Explained lists the contributions to the total of Config.Explain in chain order
+	Explained []Contribution
+
+	// Calls is the number of RPC calls the scan made, retries and every request of a batch included
+	Calls int64
+}
+
+// Header identifies one block and the block it builds on
+type Header struct {
+	Number     *big.Int
+	Hash       string
+	ParentHash string
+
+	// BaseFee is the EIP-1559 base fee per gas in wei, nil for blocks from before London
+	BaseFee *big.Int
+}
+
+// Flow is the gross activity of one address, amounts are in wei
+type Flow struct {
+	// Sent is everything the address paid, transferred value and gas fees alike
+	Sent *big.Int
+
+	// Received is everything transferred to the address
+	Received *big.Int
+
+	// Transactions counts the transactions the address sent or received, zero value calls included
+	Transactions int
+}
+
+// Stats are activity totals over the successfully scanned blocks
+type Stats struct {
+	// Transactions is the number of transactions, including zero value calls
+	Transactions int
+
+	// Transfers is the number of transactions that moved a non-zero value
+	Transfers int
+
+	// Volume is the total value of those transfers in wei, gas not included
+	Volume *big.Int
+
+	// Burned is the wei destroyed by the EIP-1559 base fee, base fee times gas used of every block
+	// It covers whole blocks whatever the filters, blocks from before London burn nothing
+	Burned *big.Int
+
+	// Largest is the transfer that moved the most value, nil without any transfers
+	// Equal values go to the earliest transaction, by block and then position in the block
+	Largest *Transfer
+}
+
+// Transfer is a single transaction that moved ETH
+type Transfer struct {
+	Block *big.Int `json:"block"`
+	Index int      `json:"index"`
+	Hash  string   `json:"hash"`
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Value *big.Int `json:"value"`
+}
+
+// Whether t ranks above other as the largest transfer
+func (t *Transfer) beats(other *Transfer) bool {
+	if other == nil {
+		return true
+	}
+	if c := t.Value.Cmp(other.Value); c != 0 {
+		return c > 0
+	}
+	if c := t.Block.Cmp(other.Block); c != 0 {
+		return c < 0
+	}
+
+	return t.Index < other.Index
+}
+
+func largerTransfer(a, b *Transfer) *Transfer {
+	if b != nil && b.beats(a) {
+		return b
+	}
+
+	return a
+}
+
+// Scan fetches every block in the inclusive range from..to and returns the net balance change per address
+// Block numbers are big.Int so there is no overflow cliff, only the number of blocks in the range has to fit into an int
+// If ctx is cancelled no new RPC calls are made, requests already sent still get their answer
+// Blocks that were cut short are left out like the ones never started, and the totals aggregated so far are returned with ctx.Err()
+func Scan(ctx context.Context, client Client, from, to *big.Int, config Config) (*Result, error) {
+	if config.Workers < 1 {
+		return nil, fmt.Errorf("workers must be at least 1, got %d", config.Workers)
+	}
+
+	span := new(big.Int).Sub(to, from)
+	span.Add(span, big.NewInt(1))
+
+	if span.Sign() < 0 {
+		return nil, fmt.Errorf("invalid block range %d to %d", from, to)
+	}
+
+	if !span.IsInt64() || span.Int64() > math.MaxInt32 {
+		return nil, fmt.Errorf("block range %d to %d is too large", from, to)
+	}
+
+	// Pick up where an interrupted run of the same scan left off
+	totals := newAggregator(config)
+	skip := map[string]bool{}
+
+	if config.Checkpoint != "" {
+		saved, err := loadCheckpoint(config.Checkpoint, from, to, config)
+		if err != nil {
+			return nil, err
+		}
+
+		if saved != nil {
+			if err := totals.restore(saved); err != nil {
+				return nil, err
+			}
+			for _, blockNum := range saved.Completed {
+				skip[blockNum.String()] = true
+				config.Seen.Add(blockNum)
+			}
+		}
+	}
+
+	// Configure our worker pool and the IO channels
+	// We send the block to parse and receive the outcome of parsing it
+	// Both channels stay small whatever the range, so memory only grows with the number of addresses
+	count := int(span.Int64())
+
+	// A sample replaces the range with the picked offsets into it
+	offset := func(i int) int { return i }
+	sampled := config.Sample > 0 && config.Sample < count
+	if sampled {
+		picked := sampleOffsets(count, config.Sample, config.Seed)
+		offset = func(i int) int { return picked[i] }
+		count = len(picked)
+	}
+	input := make(chan []*big.Int, config.Workers)
+	output := make(chan blockResult, config.Workers)
+
+	// The WaitGroup is local so concurrent or repeated scans never share a counter
+	var wg sync.WaitGroup
+	s := newScanner(client, config)
+
+	s.startReceipts()
+
+	// Increment waitgroup counter and create go routines
+	for i := 0; i < config.Workers; i++ {
+		wg.Add(1)
+		go s.parseBlocks(ctx, &wg, input, output)
+	}
+
+	// Producer: load up input channel with jobs
+	// Each job is a batch of block numbers to be processed, a single block unless batching is on
+	// It runs in the background because input is bounded, the aggregation below has to drain output meanwhile
+	// Stop enqueueing as soon as the scan is cancelled
+	batchSize := 1
+	if config.BatchSize > 1 && s.batcher() != nil {
+		batchSize = config.BatchSize
+	}
+
+	go func() {
+		// Close input channel once no more jobs are being sent to it
+		defer close(input)
+
+		batch := make([]*big.Int, 0, batchSize)
+		for i := 0; i < count; i++ {
+			x := new(big.Int).Add(from, big.NewInt(int64(offset(i))))
+			if skip[x.String()] || config.Seen.Contains(x) {
+				continue
+			}
+
+			batch = append(batch, x)
+			if len(batch) < batchSize {
+				continue
+			}
+
+			select {
+			case input <- batch:
+			case <-ctx.Done():
+				return
+			}
+			batch = make([]*big.Int, 0, batchSize)
+		}
+
+		// Whatever is left over makes a smaller last batch
+		if len(batch) > 0 {
+			select {
+			case input <- batch:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	// Close output channel once all workers have finished processing
+	// This runs in the background so we can aggregate while the workers are still busy
+	go s.closeWhenDone(&wg, output)
+
+	done := len(skip)
+	start := time.Now()
+	lastFlush := start
+
+	// Read each block result from output channel as it arrives
+	for result := range output {
+		done++
+
+		switch {
+		// Not a failure of the block, a checkpoint picks it up again next run
+		case result.err != nil && stoppedBy(ctx, result.err):
+			s.log.Debug("block cut short by the end of the scan", "block", result.block)
+		// Another scan sharing the set got there first, its count already has this block
+		case result.err == nil && !config.Seen.Add(result.block):
+			s.log.Debug("skipping block counted by another scan", "block", result.block)
+		default:
+			totals.add(result)
+			config.Metrics.block(result.err != nil)
+			if config.OnBlock != nil && result.err == nil {
+				config.OnBlock(result.block, totals.netChanges(result))
+			}
+		}
+
+		// Writing the checkpoint on every block would dominate IO on big scans
+		if config.Checkpoint != "" && time.Since(lastFlush) >= config.CheckpointInterval {
+			if err := saveCheckpoint(config.Checkpoint, from, to, config, totals); err != nil {
+				s.log.Warn("cannot write checkpoint", "path", config.Checkpoint, "err", err)
+			}
+			lastFlush = time.Now()
+		}
+
+		if config.Progress != nil {
+			config.Progress(done, count)
+		}
+	}
+
+	// A finished scan has nothing left to resume, anything else keeps its checkpoint for the next run
+	if config.Checkpoint != "" {
+		if ctx.Err() == nil && len(totals.failed) == 0 {
+			os.Remove(config.Checkpoint)
+		} else if err := saveCheckpoint(config.Checkpoint, from, to, config, totals); err != nil {
+			s.log.Warn("cannot write checkpoint", "path", config.Checkpoint, "err", err)
+		}
+	}
+
+	s.log.Info("scan finished", "blocks", done-len(skip), "failed", len(totals.failed), "resumed", len(skip), "duration", time.Since(start).Round(time.Millisecond))
+
+	result := s.result(ctx, totals, count)
+	result.From, result.To, result.Sampled = from, to, sampled
+
+	return result, ctx.Err()
+}
+
+// Pick n distinct offsets out of 0..count-1 and return them in ascending order
+// Floyd's algorithm draws exactly n numbers, so a small sample of a huge range stays cheap
+func sampleOffsets(count, n int, seed int64) []int {
+	random := rand.New(rand.NewSource(seed))
+	chosen := make(map[int]bool, n)
+
+	for j := count - n; j < count; j++ {
+		if t := random.Intn(j + 1); chosen[t] {
+			chosen[j] = true
+		} else {
+			chosen[t] = true
+		}
+	}
+
+	offsets := make([]int, 0, n)
+	for offset := range chosen {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+
+	return offsets
+}
+
+// Receipts get their own pool, otherwise a block with hundreds of transactions fetches them one at a time
+func (s *scanner) startReceipts() {
+	if !s.config.IncludeGas && !s.config.Tokens {
+		return
+	}
+
+	receiptWorkers := s.config.ReceiptWorkers
+	if receiptWorkers < 1 {
+		receiptWorkers = s.config.Workers
+	}
+
+	s.receiptJobs = make(chan receiptJob)
+	for i := 0; i < receiptWorkers; i++ {
+		go s.receiptWorker(s.receiptJobs)
+	}
+}
+
+// Close output once all block workers are done
+// They are the only ones handing out receipt jobs, so the receipt pool can stop with them
+func (s *scanner) closeWhenDone(wg *sync.WaitGroup, output chan blockResult) {
+	wg.Wait()
+	if s.receiptJobs != nil {
+		close(s.receiptJobs)
+	}
+	close(output)
+}
+
+// Turn the final totals into a Result
+func (s *scanner) result(ctx context.Context, totals *aggregator, count int) *Result {
+	balances, tokens, failed := totals.balances, totals.tokens, totals.failed
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Cmp(failed[j]) < 0 })
+	sortContributions(totals.explained)
+
+	// Look up the symbol and decimals of each token we saw so amounts can be displayed
+	tokenInfo := map[string]TokenInfo{}
+	for holder := range tokens {
+		if _, ok := tokenInfo[holder.Token]; !ok && ctx.Err() == nil {
+			tokenInfo[holder.Token] = s.tokenInfo(ctx, holder.Token)
+		}
+	}
+	for collection := range totals.nfts {
+		if _, ok := tokenInfo[collection]; !ok && ctx.Err() == nil {
+			tokenInfo[collection] = s.tokenInfo(ctx, collection)
+		}
+	}
+
+	addresses := len(balances)
+	if totals.addresses != nil {
+		addresses = len(totals.addresses)
+	}
+
+	return &Result{Balances: balances, Addresses: addresses, Flows: totals.flows, Failed: failed, FailedHashes: totals.failedHashes, Blocks: count, Tokens: tokens, TokenInfo: tokenInfo, NFTs: totals.nfts, NFTHolders: totals.nftHolders, Stats: totals.stats, Gas: totals.gas, Histogram: totals.histogram, Headers: sortHeaders(totals.headers), Explained: totals.explained, Calls: s.calls.Load()}
+}
+
+func sortHeaders(headers []Header) []Header {
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Number.Cmp(headers[j].Number) < 0 })
+	return headers
+}
+
+// The outcome of parsing a single block, sent from the workers to the aggregator
+type blockResult struct {
+	block   *big.Int
+	changes []BalanceChange
+	tokens  []TokenChange
+	nfts    []NFTTransfer
+	err     error
+
+	// Changes to the address of Config.Explain
+	explained []Contribution
+
+	hash       string
+	parentHash string
+	baseFee    *big.Int
+
+	transactions int
+	transfers    int
+	volume       big.Int
+	burned       big.Int
+	largest      *Transfer
+	gas          GasPrices
+	histogram    []int
+
+	// Number of transactions each address took part in
+	participants map[string]int
+}
+
+// scanner holds the state shared by all workers of a single scan
+type scanner struct {
+	client  Client
+	batch   Batcher
+	config  Config
+	limiter *rate.Limiter
+	breaker *breaker
+	tokens  tokenCache
+	cache   blockCache
+	log     *slog.Logger
+
+	// Config.Between and Config.Explain normalized, empty when not set
+	between [2]string
+	explain string
+
+	// Jobs for the receipt pool, nil when the scan needs no receipts
+	receiptJobs chan receiptJob
+
+	// Set once the endpoint turned out not to support tracing, so the warning is only logged once
+	traceUnsupported atomic.Bool
+
+	// Every RPC call the workers made, counted by the client wrappers
+	calls atomic.Int64
+}
+
+func newScanner(client Client, config Config) *scanner {
+	// One limiter for the whole scan so the cap applies across workers
+	limit := rate.Inf
+	if config.RPS > 0 {
+		limit = rate.Limit(config.RPS)
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	s := &scanner{
+		log:     logger,
+		config:  config,
+		limiter: rate.NewLimiter(limit, 1),
+		cache:   newBlockCache(config.CacheDir, config.Network),
+		breaker: newBreaker(config.BreakerThreshold, config.BreakerCooldown, logger),
+	}
+
+	s.client = countingClient{client: client, calls: &s.calls}
+	if batcher := batcherOf(client); batcher != nil {
+		s.batch = countingBatcher{batcher: batcher, calls: &s.calls}
+	}
+
+	if config.Explain != "" {
+		s.explain = NormalizeAddress(config.Explain)
+	}
+
+	if len(config.Between) == 2 {
+		s.between = [2]string{NormalizeAddress(config.Between[0]), NormalizeAddress(config.Between[1])}
+	}
+
+	return s
+}
+
+func (s *scanner) parseBlocks(ctx context.Context, wg *sync.WaitGroup, input chan []*big.Int, output chan blockResult) {
+	defer wg.Done()
+
+	// Keep pulling batches until the input channel is drained or the scan is cancelled
+	for batch := range input {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Fetch Block Data from Blockchain, all of the batch in one request when there is more than one block
+		var fetched []fetchedBlock
+		if len(batch) > 1 {
+			fetched = s.fetchBlocks(ctx, batch)
+		} else {
+			block, err := s.fetchBlock(ctx, batch[0])
+			fetched = []fetchedBlock{{block: block, err: err}}
+		}
+
+		for i, blockNum := range batch {
+			result := blockResult{block: blockNum, err: fetched[i].err}
+			if result.err == nil {
+				result = s.parseBlock(ctx, blockNum, fetched[i].block)
+			}
+
+			if err := result.err; err != nil && !stoppedBy(ctx, err) {
+				s.log.Error("cannot fetch block", "block", blockNum, "err", err)
+			}
+
+			// Consumer: Send the proccessed chunk back to the output channel
+			// The aggregator drains output until it is closed, so this never blocks forever
+			output <- result
+		}
+	}
+}
+
+// Turn the transactions of a fetched block into balance changes
+// Any failed RPC call fails the whole block so totals never include half a block
+func (s *scanner) parseBlock(ctx context.Context, blockNum *big.Int, block *eth.Block) blockResult {
+	result := blockResult{block: blockNum, participants: map[string]int{}}
+	if s.config.Histogram != nil {
+		result.histogram = make([]int, len(s.config.Histogram)+1)
+	}
+
+	result.hash, result.parentHash, result.baseFee = block.Hash, block.ParentHash, block.BaseFeePerGas
+
+	// Every transaction burns the base fee for each unit of gas it used, so the block's gas used covers them all
+	if block.BaseFeePerGas != nil && block.GasUsed != nil {
+		result.burned.Mul(block.BaseFeePerGas, block.GasUsed)
+	}
+
+	block.Transactions = s.uniqueTransactions(blockNum, block.Transactions)
+
+	// Hashes are all there is to count
+	if !s.fullTransactions() {
+		result.transactions = len(block.Transactions)
+		return result
+	}
+
+	balances := []BalanceChange{}
+	tokens := []TokenChange{}
+	nfts := []NFTTransfer{}
+	var err error
+
+	// Traces line up with the transactions of the block, one entry each
+	var traces []traceResult
+	if s.config.Trace {
+		var ok bool
+		traces, ok, err = s.fetchTraces(ctx, blockNum)
+		if err != nil {
+			result.err = err
+			return result
+		}
+		if ok && len(traces) != len(block.Transactions) {
+			result.err = fmt.Errorf("got %d traces for %d transactions", len(traces), len(block.Transactions))
+			return result
+		}
+	}
+
+	// Iterate through all transactions in the block
+	// Record the balance change for each address
+	// Addresses are normalized first so different casings of one address share a single total
+	// The sender loses the value and the receiver gains it, so the totals are net deltas
+	// Gas and token transfers both live in the receipt, so fetch each one at most once
+	// They are all requested up front on the receipt pool and looked up by hash below
+	var receipts map[string]*Receipt
+	if s.receiptJobs != nil {
+		hashes := []string{}
+		for _, tx := range block.Transactions {
+			if !s.skipTransaction(tx) {
+				hashes = append(hashes, tx.Hash)
+			}
+		}
+
+		receipts, err = s.fetchReceipts(ctx, hashes)
+		if err != nil {
+			result.err = err
+			return result
+		}
+	}
+
+	for i, tx := range block.Transactions {
+		tx.From = NormalizeAddress(tx.From)
+		tx.To = NormalizeAddress(tx.To)
+
+		if s.skipTransaction(tx) {
+			continue
+		}
+
+		result.transactions++
+		mark := len(balances)
+
+		if s.config.GasStats {
+			result.gas.add(tx)
+		}
+
+		// A transaction counts once per address, so sending to yourself is still one transaction
+		result.participants[tx.From]++
+		if !isContractCreation(tx) && tx.To != tx.From {
+			result.participants[tx.To]++
+		}
+
+		// !!! If the value is zero this is most likely a smart contract call or a token transfer !!!
+		// The value of ERC20 token transactions is not processed in the same way as a normal transaction
+		// The value is always zero, but the token transfer is processed by the smart contract
+		// Thus we can ignore these transactions since they will always be zero
+		if tx.Value.Sign() > 0 {
+			result.transfers++
+			result.volume.Add(&result.volume, tx.Value)
+			result.largest = largerTransfer(result.largest, &Transfer{Block: blockNum, Index: i, Hash: tx.Hash, From: tx.From, To: tx.To, Value: new(big.Int).Set(tx.Value)})
+			if result.histogram != nil {
+				result.histogram[bucket(s.config.Histogram, tx.Value)]++
+			}
+
+			// Both sides get their own big.Int, dereferencing tx.Value would share its digits with the block
+			// and a later change to either one would silently change the other
+			sent := new(big.Int).Neg(tx.Value)
+			received := new(big.Int).Set(tx.Value)
+
+			balances = append(balances, BalanceChange{Balance: *sent, Address: tx.From})
+
+			// Contract creations have no To, the value ends up in the new contract
+			// Only the sender is debited so the results never get a blank address row
+			if !isContractCreation(tx) {
+				balances = append(balances, BalanceChange{Balance: *received, Address: tx.To})
+			}
+		} else if s.config.IncludeZero {
+			balances = append(balances, BalanceChange{Address: tx.From})
+			if !isContractCreation(tx) {
+				balances = append(balances, BalanceChange{Address: tx.To})
+			}
+		}
+
+		// Value moved by the contracts this transaction called never shows up in tx.Value
+		if traces != nil {
+			balances = append(balances, internalTransfers(traces[i].Result)...)
+		}
+
+		if receipt, ok := receipts[tx.Hash]; ok {
+			// Gas is paid on every transaction, including zero value contract calls
+			// The sender is debited gasUsed * gasPrice on top of the transferred value
+			if s.config.IncludeGas {
+				fee := new(big.Int).Neg(gasFee(tx, receipt))
+				balances = append(balances, BalanceChange{Balance: *fee, Address: tx.From})
+			}
+
+			// Token transfers show up as Transfer events emitted by the token contract
+			if s.config.Tokens {
+				for _, log := range receipt.Logs {
+					if changes, ok := decodeTransfer(log); ok {
+						tokens = append(tokens, changes...)
+					} else if nft, ok := decodeNFTTransfer(log); ok {
+						nfts = append(nfts, nft)
+					}
+				}
+			}
+		}
+
+		// Everything this transaction changed for the explained address, value, gas and internal calls alike
+		if s.explain != "" {
+			result.explained = append(result.explained, contributions(s.explain, blockNum, i, tx.Hash, balances[mark:])...)
+		}
+	}
+
+	result.changes = balances
+	result.tokens = tokens
+	result.nfts = nfts
+
+	return result
+}
+
+// Whether blocks have to be fetched with full transaction objects rather than just their hashes
+func (s *scanner) fullTransactions() bool {
+	config := s.config
+
+	return !config.TxCountsOnly || config.IncludeGas || config.Tokens || config.Trace || config.GasStats ||
+		config.Histogram != nil || config.TxMinWei != nil && config.TxMinWei.Sign() > 0 || len(config.Between) > 0
+}
+
+// Drop repeated entries of a transaction so it is only counted once
+// A real block never lists a transaction twice, only a misbehaving node or proxy can send one like that
+func (s *scanner) uniqueTransactions(blockNum *big.Int, txs []eth.Transaction) []eth.Transaction {
+	seen := make(map[string]bool, len(txs))
+	unique := make([]eth.Transaction, 0, len(txs))
+
+	for _, tx := range txs {
+		hash := strings.ToLower(tx.Hash)
+		if hash != "" && seen[hash] {
+			s.log.Warn("dropping duplicate transaction from block", "block", blockNum, "hash", tx.Hash)
+			continue
+		}
+
+		seen[hash] = true
+		unique = append(unique, tx)
+	}
+
+	return unique
+}
+
+// Transactions below the threshold are dropped whole, as if they weren't in the block
+// This works per transaction, many small transfers to one address never add up to a kept one
+// With Between set everything not between the pair is dropped the same way
+func (s *scanner) skipTransaction(tx eth.Transaction) bool {
+	if s.config.TxMinWei != nil && tx.Value.Cmp(s.config.TxMinWei) < 0 {
+		return true
+	}
+
+	if s.between[0] != "" {
+		from, to := NormalizeAddress(tx.From), NormalizeAddress(tx.To)
+		return !(from == s.between[0] && to == s.between[1] || from == s.between[1] && to == s.between[0])
+	}
+
+	return false
+}
+
+// Contract creation transactions have an empty or null To address
+func isContractCreation(tx eth.Transaction) bool {
+	return tx.To == ""
+}
@@ -0,0 +1,666 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+const (
+	alice = "0x000000000000000000000000000000000000000a"
+	bob   = "0x000000000000000000000000000000000000000b"
+	carol = "0x000000000000000000000000000000000000000c"
+)
+
+// One canned transaction, value and gas in wei
+type fakeTx struct {
+	from, to string
+	value    int64
+	gasUsed  int64
+	gasPrice int64
+}
+
+// A node answering eth_getBlockByNumber and eth_getTransactionReceipt from canned blocks
+// Blocks missing from txs hold one transfer of n wei from alice to bob
+// A block in failures fails that many attempts before it answers, -1 fails them all
+// Calls wait for release when it is set and are announced on called when that is set
+// Every answer takes delay, like a slow node
+type fakeChain struct {
+	txs      map[uint64][]fakeTx
+	failures map[uint64]int
+	release  chan struct{}
+	called   chan struct{}
+	delay    time.Duration
+
+	mu       sync.Mutex
+	attempts map[uint64]int
+}
+
+func (c *fakeChain) transactions(n uint64) []fakeTx {
+	if txs, ok := c.txs[n]; ok {
+		return txs
+	}
+	return []fakeTx{{from: alice, to: bob, value: int64(n)}}
+}
+
+func txHash(n uint64, i int) string {
+	return fmt.Sprintf("0x%056x%08x", n, i)
+}
+
+// How many times block n was asked for
+func (c *fakeChain) fetched(n uint64) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempts[n]
+}
+
+// How many different blocks were asked for
+func (c *fakeChain) blocks() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.attempts)
+}
+
+func (c *fakeChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	if c.called != nil {
+		select {
+		case c.called <- struct{}{}:
+		default:
+		}
+	}
+	if c.release != nil {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if c.delay > 0 {
+		time.Sleep(c.delay)
+	}
+
+	switch method {
+	case "eth_getBlockByNumber":
+		n, err := strconv.ParseUint(params[0].(string), 0, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		if c.attempts == nil {
+			c.attempts = map[uint64]int{}
+		}
+		c.attempts[n]++
+		attempt := c.attempts[n]
+		c.mu.Unlock()
+
+		if failures := c.failures[n]; failures < 0 || attempt <= failures {
+			return nil, errors.New("node unavailable")
+		}
+
+		txs := []interface{}{}
+		for i, tx := range c.transactions(n) {
+			// Contract creations have no receiver, nodes send null
+			var to interface{}
+			if tx.to != "" {
+				to = tx.to
+			}
+
+			txs = append(txs, map[string]interface{}{
+				"hash":     txHash(n, i),
+				"from":     tx.from,
+				"to":       to,
+				"value":    fmt.Sprintf("%#x", tx.value),
+				"gasPrice": fmt.Sprintf("%#x", tx.gasPrice),
+			})
+		}
+
+		return &jsonrpc.RPCResponse{Result: map[string]interface{}{
+			"number":       fmt.Sprintf("%#x", n),
+			"hash":         fmt.Sprintf("0x%064x", n),
+			"parentHash":   fmt.Sprintf("0x%064x", n-1),
+			"transactions": txs,
+		}}, nil
+
+	case "eth_getTransactionReceipt":
+		hash := params[0].(string)
+		n, err := strconv.ParseUint(hash[2:58], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		i, err := strconv.ParseUint(hash[58:], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		tx := c.transactions(n)[i]
+
+		return &jsonrpc.RPCResponse{Result: map[string]interface{}{
+			"transactionHash":   hash,
+			"from":              tx.from,
+			"to":                tx.to,
+			"gasUsed":           fmt.Sprintf("%#x", tx.gasUsed),
+			"effectiveGasPrice": fmt.Sprintf("%#x", tx.gasPrice),
+			"logs":              []interface{}{},
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected %s call", method)
+}
+
+func scan(t *testing.T, chain Client, from, to int64, config Config) *Result {
+	t.Helper()
+
+	result, err := Scan(context.Background(), chain, big.NewInt(from), big.NewInt(to), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func wantBalances(t *testing.T, result *Result, want map[string]int64) {
+	t.Helper()
+
+	if len(result.Balances) != len(want) {
+		t.Errorf("got %d addresses %v, want %d", len(result.Balances), result.Balances, len(want))
+	}
+	for address, change := range want {
+		if got := result.Balances[address]; got == nil || got.Int64() != change {
+			t.Errorf("%s changed by %v, want %d", address, got, change)
+		}
+	}
+}
+
+func TestScanAggregatesTransfers(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 5}},
+		2: {{from: bob, to: carol, value: 3}, {from: alice, to: carol, value: 0}},
+		3: {{from: alice, to: carol, value: 2}},
+	}}
+
+	result := scan(t, chain, 1, 3, Config{Workers: 2})
+
+	wantBalances(t, result, map[string]int64{alice: -7, bob: 2, carol: 5})
+	if flow := result.Flows[bob]; flow.Sent.Int64() != 3 || flow.Received.Int64() != 5 || flow.Transactions != 2 {
+		t.Errorf("bob's flow %+v", flow)
+	}
+	if result.Stats.Transactions != 4 || result.Stats.Transfers != 3 || result.Stats.Volume.Int64() != 10 {
+		t.Errorf("stats %+v", result.Stats)
+	}
+	if result.Stats.Largest == nil || result.Stats.Largest.Value.Int64() != 5 || result.Stats.Largest.Block.Int64() != 1 {
+		t.Errorf("largest transfer %+v", result.Stats.Largest)
+	}
+	if result.Blocks != 3 || len(result.Failed) != 0 || result.Calls != 3 {
+		t.Errorf("blocks %d, failed %v, calls %d", result.Blocks, result.Failed, result.Calls)
+	}
+}
+
+func TestScanDeductsGasFromReceipts(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 100, gasUsed: 21000, gasPrice: 2}},
+		2: {{from: bob, to: carol, value: 0, gasUsed: 50000, gasPrice: 1}},
+	}}
+
+	result := scan(t, chain, 1, 2, Config{Workers: 2, IncludeGas: true})
+
+	// The zero value call still pays for its gas, the contract it called gets nothing
+	wantBalances(t, result, map[string]int64{alice: -42100, bob: 100 - 50000})
+
+	// One call per block and one per receipt
+	if result.Calls != 4 {
+		t.Errorf("calls %d, want 4", result.Calls)
+	}
+}
+
+func TestScanFetchesEveryBlockOnce(t *testing.T) {
+	for _, workers := range []int{1, 3, 16} {
+		chain := &fakeChain{}
+		result := scan(t, chain, 1, 50, Config{Workers: workers})
+
+		// 1+2+...+50 wei went from alice to bob
+		wantBalances(t, result, map[string]int64{alice: -1275, bob: 1275})
+		for n := uint64(1); n <= 50; n++ {
+			if got := chain.fetched(n); got != 1 {
+				t.Errorf("%d workers fetched block %d %d times", workers, n, got)
+			}
+		}
+	}
+}
+
+func TestScanReportsProgressPerBlock(t *testing.T) {
+	var calls []int
+	result := scan(t, &fakeChain{}, 10, 19, Config{Workers: 4, Progress: func(done, total int) {
+		if total != 10 {
+			t.Errorf("total %d, want 10", total)
+		}
+		calls = append(calls, done)
+	}})
+
+	if len(calls) != 10 || calls[9] != 10 || result.Blocks != 10 {
+		t.Errorf("progress %v", calls)
+	}
+}
+
+func TestScanLeavesFailedBlocksOut(t *testing.T) {
+	chain := &fakeChain{failures: map[uint64]int{2: -1}}
+
+	result := scan(t, chain, 1, 3, Config{Workers: 2, Retries: 2})
+
+	if len(result.Failed) != 1 || result.Failed[0].Int64() != 2 {
+		t.Fatalf("failed %v, want block 2", result.Failed)
+	}
+	wantBalances(t, result, map[string]int64{alice: -4, bob: 4})
+
+	// The first attempt and both retries
+	if got := chain.fetched(2); got != 3 {
+		t.Errorf("block 2 fetched %d times, want 3", got)
+	}
+}
+
+func TestScanRetriesTransientFailures(t *testing.T) {
+	chain := &fakeChain{failures: map[uint64]int{2: 1}}
+
+	result := scan(t, chain, 1, 3, Config{Workers: 2, Retries: 1, RetryDelay: time.Millisecond})
+
+	if len(result.Failed) != 0 {
+		t.Fatalf("failed %v", result.Failed)
+	}
+	wantBalances(t, result, map[string]int64{alice: -6, bob: 6})
+	if result.Calls != 4 {
+		t.Errorf("calls %d, want 4 with the retry", result.Calls)
+	}
+}
+
+func TestScanStopsWhenCancelled(t *testing.T) {
+	chain := &fakeChain{release: make(chan struct{}), called: make(chan struct{}, 1)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-chain.called
+		cancel()
+
+		// A request already sent runs to its answer
+		close(chain.release)
+	}()
+
+	result, err := Scan(ctx, chain, big.NewInt(1), big.NewInt(1000), Config{Workers: 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	// Blocks that never ran are not failures, a later run picks them up
+	if len(result.Failed) != 0 {
+		t.Errorf("%d blocks counted as failed", len(result.Failed))
+	}
+	if got := chain.blocks(); got > 10 {
+		t.Errorf("%d blocks fetched after the cancellation", got)
+	}
+}
+
+func TestScanRejectsBadArguments(t *testing.T) {
+	if _, err := Scan(context.Background(), &fakeChain{}, big.NewInt(1), big.NewInt(2), Config{}); err == nil {
+		t.Error("scan without workers ran")
+	}
+	if _, err := Scan(context.Background(), &fakeChain{}, big.NewInt(5), big.NewInt(1), Config{Workers: 1}); err == nil {
+		t.Error("backwards range ran")
+	}
+}
+
+func TestScanRunsTwiceInARow(t *testing.T) {
+	chain := &fakeChain{}
+
+	// Nothing is left over from the first run, the second one counts only its own range
+	first := scan(t, chain, 1, 20, Config{Workers: 4})
+	second := scan(t, chain, 21, 30, Config{Workers: 4})
+
+	wantBalances(t, first, map[string]int64{alice: -210, bob: 210})
+	wantBalances(t, second, map[string]int64{alice: -255, bob: 255})
+	if first.Blocks != 20 || second.Blocks != 10 {
+		t.Errorf("blocks %d and %d", first.Blocks, second.Blocks)
+	}
+}
+
+func TestScanWithTinyBuffersCoversLargeRange(t *testing.T) {
+	chain := &fakeChain{}
+
+	// One worker means the input and output channels hold a single entry each
+	const blocks = 10000
+	result := scan(t, chain, 1, blocks, Config{Workers: 1})
+
+	if result.Blocks != blocks || len(result.Failed) != 0 {
+		t.Fatalf("blocks %d, failed %d", result.Blocks, len(result.Failed))
+	}
+	if got := chain.blocks(); got != blocks {
+		t.Fatalf("fetched %d blocks, want %d", got, blocks)
+	}
+	for n := uint64(1); n <= blocks; n++ {
+		if got := chain.fetched(n); got != 1 {
+			t.Fatalf("block %d fetched %d times", n, got)
+		}
+	}
+
+	total := int64(blocks * (blocks + 1) / 2)
+	wantBalances(t, result, map[string]int64{alice: -total, bob: total})
+}
+
+func TestScanReturnsPartialTotalsWhenTheBudgetRunsOut(t *testing.T) {
+	chain := &fakeChain{delay: 5 * time.Millisecond}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := Scan(ctx, chain, big.NewInt(1), big.NewInt(100000), Config{Workers: 2})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+
+	// What was counted before the deadline is kept, and adds up like any other total
+	gained := result.Balances[bob]
+	if gained == nil || gained.Sign() <= 0 || new(big.Int).Add(gained, result.Balances[alice]).Sign() != 0 {
+		t.Fatalf("partial balances %v", result.Balances)
+	}
+	if len(result.Failed) != 0 {
+		t.Errorf("%d blocks counted as failed", len(result.Failed))
+	}
+}
+
+func TestScanBudgetCutsRetriesShort(t *testing.T) {
+	chain := &fakeChain{failures: map[uint64]int{1: -1}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Neither the backoff nor an open breaker may outlast the budget
+	config := Config{Workers: 1, Retries: 100, RetryDelay: time.Hour, BreakerThreshold: 1, BreakerCooldown: time.Hour}
+
+	start := time.Now()
+	result, err := Scan(ctx, chain, big.NewInt(1), big.NewInt(1), config)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("scan took %s past a budget of 100ms", took)
+	}
+
+	// The block ran out of time rather than retries, so it is left for the next run instead of failed
+	if len(result.Failed) != 0 {
+		t.Errorf("failed %v", result.Failed)
+	}
+}
+
+func TestScanBudgetEndsRequestsInFlight(t *testing.T) {
+	// The node never answers and there is no per-request timeout, only the budget can end the call
+	chain := &fakeChain{release: make(chan struct{})}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		_, err := Scan(ctx, chain, big.NewInt(1), big.NewInt(10), Config{Workers: 2})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want context.DeadlineExceeded", err)
+		}
+		if took := time.Since(start); took > time.Second {
+			t.Errorf("scan took %s past a budget of 100ms", took)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("scan still waits for requests in flight long after the budget ran out")
+	}
+}
+
+// Answers every block with one transfer of 1 wei from alice to bob, whatever its number
+type bigChain struct {
+	mu   sync.Mutex
+	tags []string
+}
+
+func (c *bigChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	switch method {
+	case "eth_getBlockByNumber":
+		tag := params[0].(string)
+		n, ok := new(big.Int).SetString(tag, 0)
+		if !ok {
+			return nil, fmt.Errorf("bad block %s", tag)
+		}
+
+		c.mu.Lock()
+		c.tags = append(c.tags, tag)
+		c.mu.Unlock()
+
+		return &jsonrpc.RPCResponse{Result: map[string]interface{}{
+			"number":     tag,
+			"hash":       fmt.Sprintf("0x%064x", n),
+			"parentHash": fmt.Sprintf("0x%064x", new(big.Int).Sub(n, big.NewInt(1))),
+			"transactions": []interface{}{map[string]interface{}{
+				"hash":  fmt.Sprintf("0x%064x", n),
+				"from":  alice,
+				"to":    bob,
+				"value": "0x1",
+			}},
+		}}, nil
+
+	case "eth_getTransactionReceipt":
+		return &jsonrpc.RPCResponse{Result: map[string]interface{}{
+			"transactionHash":   params[0],
+			"gasUsed":           "0x0",
+			"effectiveGasPrice": "0x0",
+			"logs":              []interface{}{},
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("unexpected %s call", method)
+}
+
+// Block numbers past what fits in an int64 or even a uint64 are asked for as they are
+func TestScanHugeBlockNumbers(t *testing.T) {
+	from, _ := new(big.Int).SetString("18446744073709551617", 10) // 2^64 + 1
+	to := new(big.Int).Add(from, big.NewInt(2))
+
+	chain := &bigChain{}
+	result, err := Scan(context.Background(), chain, from, to, Config{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantBalances(t, result, map[string]int64{alice: -3, bob: 3})
+	if result.Blocks != 3 || result.From.Cmp(from) != 0 || result.To.Cmp(to) != 0 {
+		t.Errorf("scanned %d blocks from %v to %v", result.Blocks, result.From, result.To)
+	}
+
+	want := map[string]bool{"0x10000000000000001": true, "0x10000000000000002": true, "0x10000000000000003": true}
+	for _, tag := range chain.tags {
+		if !want[tag] {
+			t.Errorf("asked for block %s", tag)
+		}
+	}
+}
+
+func TestScanRejectsAHugeRange(t *testing.T) {
+	to, _ := new(big.Int).SetString("18446744073709551617", 10)
+
+	if _, err := Scan(context.Background(), &bigChain{}, big.NewInt(0), to, Config{Workers: 2}); err == nil {
+		t.Fatal("a range of 2^64 blocks was accepted")
+	}
+}
+
+// A block that keeps failing is an error, its retries only show up at debug level
+func TestFailedBlocksAreLoggedAsErrors(t *testing.T) {
+	chain := &fakeChain{failures: map[uint64]int{2: -1}}
+
+	var logs bytes.Buffer
+	config := Config{
+		Workers:    1,
+		Retries:    2,
+		RetryDelay: time.Millisecond,
+		Logger:     slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+	scan(t, chain, 1, 3, config)
+
+	levels := map[string][]string{}
+	decoder := json.NewDecoder(&logs)
+	for decoder.More() {
+		var line struct {
+			Level string          `json:"level"`
+			Msg   string          `json:"msg"`
+			Block json.RawMessage `json:"block"`
+		}
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		levels[line.Msg] = append(levels[line.Msg], line.Level+" "+string(line.Block))
+	}
+
+	if got := levels["cannot fetch block"]; len(got) != 1 || got[0] != "ERROR 2" {
+		t.Errorf("failed block logged as %v, want one ERROR for block 2", got)
+	}
+	if got := levels["retrying RPC call"]; len(got) != 2 || got[0] != "DEBUG " {
+		t.Errorf("retries logged as %v, want two at DEBUG", got)
+	}
+	if got := levels["scan finished"]; len(got) != 1 || got[0] != "INFO " {
+		t.Errorf("summary logged as %v, want one at INFO", got)
+	}
+}
+
+func TestTxMinWeiSkipsSmallTransactions(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 9, gasUsed: 1, gasPrice: 1}, {from: alice, to: bob, value: 10}},
+		2: {{from: carol, to: bob, value: 3, gasUsed: 1, gasPrice: 1}, {from: bob, to: alice, value: 11}},
+	}}
+
+	result := scan(t, chain, 1, 2, Config{Workers: 2, TxMinWei: big.NewInt(10), IncludeGas: true})
+
+	// Carol only sent 3 wei, gas included she is not in the totals at all
+	wantBalances(t, result, map[string]int64{alice: 1, bob: -1})
+	if flow := result.Flows[bob]; flow.Transactions != 2 {
+		t.Errorf("bob's flow %+v, want the two transactions of 10 wei and up", flow)
+	}
+}
+
+func TestIncludeZeroRecordsParticipants(t *testing.T) {
+	chain := &fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 5}, {from: carol, to: bob, value: 0}},
+	}}
+
+	// Without it carol only made a contract call, so she has no total
+	wantBalances(t, scan(t, chain, 1, 1, Config{Workers: 1}), map[string]int64{alice: -5, bob: 5})
+
+	result := scan(t, chain, 1, 1, Config{Workers: 1, IncludeZero: true})
+	wantBalances(t, result, map[string]int64{alice: -5, bob: 5, carol: 0})
+	if flow := result.Flows[carol]; flow == nil || flow.Transactions != 1 {
+		t.Errorf("carol's flow %+v, want one transaction", flow)
+	}
+	if flow := result.Flows[bob]; flow.Transactions != 2 {
+		t.Errorf("bob's flow %+v, want both transactions", flow)
+	}
+}
+
+func TestSampleOffsetsAreDistinctAndInRange(t *testing.T) {
+	for _, tc := range []struct{ count, n int }{{1000, 10}, {10, 10}, {5, 1}, {1 << 40, 3}} {
+		offsets := sampleOffsets(tc.count, tc.n, 42)
+		if len(offsets) != tc.n {
+			t.Fatalf("%d of %d: got %d offsets", tc.n, tc.count, len(offsets))
+		}
+		for i, offset := range offsets {
+			if offset < 0 || offset >= tc.count {
+				t.Errorf("%d of %d: offset %d out of range", tc.n, tc.count, offset)
+			}
+			// Ascending, so no offset is there twice
+			if i > 0 && offset <= offsets[i-1] {
+				t.Errorf("%d of %d: %v is not strictly ascending", tc.n, tc.count, offsets)
+			}
+		}
+	}
+
+	if one, two := sampleOffsets(1000, 10, 7), sampleOffsets(1000, 10, 7); fmt.Sprint(one) != fmt.Sprint(two) {
+		t.Errorf("same seed picked %v and %v", one, two)
+	}
+	if one, two := sampleOffsets(1000, 10, 7), sampleOffsets(1000, 10, 8); fmt.Sprint(one) == fmt.Sprint(two) {
+		t.Errorf("different seeds both picked %v", one)
+	}
+}
+
+func TestScanSampleFetchesOnlyThePickedBlocks(t *testing.T) {
+	chain := &fakeChain{}
+	result := scan(t, chain, 100, 199, Config{Workers: 3, Sample: 10, Seed: 7})
+
+	if !result.Sampled || result.Blocks != 10 {
+		t.Errorf("sampled %v with %d blocks, want 10 sampled blocks", result.Sampled, result.Blocks)
+	}
+	if got := chain.blocks(); got != 10 {
+		t.Fatalf("fetched %d blocks, want 10", got)
+	}
+
+	// Every picked block is fetched once and the totals are theirs only
+	var sum int64
+	for _, offset := range sampleOffsets(100, 10, 7) {
+		n := uint64(100 + offset)
+		if got := chain.fetched(n); got != 1 {
+			t.Errorf("block %d fetched %d times", n, got)
+		}
+		sum += int64(n)
+	}
+	wantBalances(t, result, map[string]int64{alice: -sum, bob: sum})
+
+	// A sample as big as the range is just the range
+	if result := scan(t, &fakeChain{}, 1, 5, Config{Workers: 1, Sample: 5}); result.Sampled || result.Blocks != 5 {
+		t.Errorf("sampled %v with %d blocks, want a full scan of 5", result.Sampled, result.Blocks)
+	}
+}
+
+// Lists the first transaction of every block twice, the second time with its hash in upper case
+type duplicatingChain struct {
+	fakeChain
+}
+
+func (c *duplicatingChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	r, err := c.fakeChain.Call(ctx, method, params...)
+	if err != nil || method != "eth_getBlockByNumber" {
+		return r, err
+	}
+
+	block := r.Result.(map[string]interface{})
+	txs := block["transactions"].([]interface{})
+	repeated := map[string]interface{}{}
+	for key, value := range txs[0].(map[string]interface{}) {
+		repeated[key] = value
+	}
+	repeated["hash"] = "0x" + strings.ToUpper(repeated["hash"].(string)[2:])
+	block["transactions"] = append(txs, repeated)
+
+	return r, nil
+}
+
+func TestDuplicateTransactionsAreCountedOnce(t *testing.T) {
+	chain := &duplicatingChain{fakeChain{txs: map[uint64][]fakeTx{
+		1: {{from: alice, to: bob, value: 5}, {from: bob, to: carol, value: 2}},
+		2: {{from: carol, to: alice, value: 1}},
+	}}}
+
+	var logs bytes.Buffer
+	result := scan(t, chain, 1, 2, Config{Workers: 2, Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+
+	wantBalances(t, result, map[string]int64{alice: -4, bob: 3, carol: 1})
+	if result.Stats.Transactions != 3 {
+		t.Errorf("%d transactions, want 3", result.Stats.Transactions)
+	}
+	if got := strings.Count(logs.String(), "dropping duplicate transaction from block"); got != 2 {
+		t.Errorf("%d warnings, want one for each block\n%s", got, logs.String())
+	}
+}
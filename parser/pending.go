@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoPendingBlock is returned by ScanPending when the node has no pending block to hand out
+var ErrNoPendingBlock = errors.New("the node has no pending block")
+
+// ScanPending scans the block the node is currently building, fetched with the "pending" tag
+// The node assembles it from its own mempool, so the transactions are tentative: they can be dropped,
+// replaced or land in a different block, and another node can have a different pending block altogether
+// Pending transactions have no receipts yet, so gas fees, token transfers and traces are left out
+// The result has no Headers and counts no Blocks, it only adds the transactions on top of a scan
+func ScanPending(ctx context.Context, client Client, config Config) (*Result, error) {
+	config.IncludeGas = false
+	config.Tokens = false
+	config.Trace = false
+
+	s := newScanner(client, config)
+	start := time.Now()
+
+	var raw []byte
+	err := s.call(ctx, func(ctx context.Context) error {
+		r, err := s.client.Call(ctx, "eth_getBlockByNumber", "pending", s.fullTransactions())
+		if err != nil {
+			return err
+		}
+		if r.Error != nil {
+			return r.Error
+		}
+
+		// Nodes that don't build blocks themselves answer null
+		if r.Result == nil {
+			return ErrNoPendingBlock
+		}
+
+		raw, err = json.Marshal(r.Result)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := decodeBlock(raw)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode the pending block: %w", err)
+	}
+	if block.Number == nil {
+		return nil, errors.New("the pending block has no number")
+	}
+
+	parsed := s.parseBlock(ctx, block.Number, block)
+	if parsed.err != nil {
+		return nil, parsed.err
+	}
+
+	totals := newAggregator(config)
+	totals.add(parsed)
+
+	result := s.result(ctx, totals, 0)
+	result.Headers = nil
+	result.Pending = parsed.transactions
+
+	s.log.Info("scanned the pending block", "number", block.Number, "transactions", parsed.transactions, "duration", time.Since(start).Round(time.Millisecond))
+
+	return result, nil
+}
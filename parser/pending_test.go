@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// Hands out block 7 of chain as the pending block, or null when there is none
+type pendingChain struct {
+	fakeChain
+	none bool
+}
+
+func (c *pendingChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	if method == "eth_getBlockByNumber" && params[0] == "pending" {
+		if c.none {
+			return &jsonrpc.RPCResponse{}, nil
+		}
+		params = append([]interface{}{"0x7"}, params[1:]...)
+	}
+	return c.fakeChain.Call(ctx, method, params...)
+}
+
+func TestScanPendingCountsItsTransactions(t *testing.T) {
+	chain := &pendingChain{fakeChain: fakeChain{txs: map[uint64][]fakeTx{
+		7: {{from: alice, to: bob, value: 5, gasUsed: 1, gasPrice: 1}, {from: carol, to: bob, value: 2}},
+	}}}
+
+	// There are no receipts for pending transactions, gas is left out
+	result, err := ScanPending(context.Background(), chain, Config{Workers: 1, IncludeGas: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantBalances(t, result, map[string]int64{alice: -5, bob: 7, carol: -2})
+	if result.Pending != 2 || result.Blocks != 0 || len(result.Headers) != 0 {
+		t.Errorf("pending %d, blocks %d, headers %v, want 2 tentative transactions and no blocks", result.Pending, result.Blocks, result.Headers)
+	}
+}
+
+func TestScanPendingOnTopOfARange(t *testing.T) {
+	chain := &pendingChain{}
+	result := scan(t, chain, 1, 3, Config{Workers: 1})
+
+	pending, err := ScanPending(context.Background(), chain, Config{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.Merge(pending)
+
+	wantBalances(t, result, map[string]int64{alice: -13, bob: 13})
+	if result.Pending != 1 || result.Blocks != 3 {
+		t.Errorf("pending %d over %d blocks, want 1 over 3", result.Pending, result.Blocks)
+	}
+}
+
+func TestScanPendingWithoutAPendingBlock(t *testing.T) {
+	_, err := ScanPending(context.Background(), &pendingChain{none: true}, Config{Workers: 1})
+	if !errors.Is(err, ErrNoPendingBlock) {
+		t.Errorf("got %v, want ErrNoPendingBlock", err)
+	}
+
+	empty := &pendingChain{fakeChain: fakeChain{txs: map[uint64][]fakeTx{7: {}}}}
+	result, err := ScanPending(context.Background(), empty, Config{Workers: 1})
+	if err != nil || result.Pending != 0 || len(result.Balances) != 0 {
+		t.Errorf("got %+v, %v, want an empty result", result, err)
+	}
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// JSON-RPC error code most node providers use for "limit exceeded"
+const rpcLimitExceeded = -32005
+
+// Providers that mention a wait time usually phrase it like "retry after 3s" or "Retry-After: 3"
+var retryAfterPattern = regexp.MustCompile(`(?i)retry[- ]after\W*(\d+)`)
+
+// IsRateLimited reports whether err means the endpoint is throttling us rather than failing
+// GetBlock answers with HTTP 429, other providers use a JSON-RPC error with a 200 status
+func IsRateLimited(err error) bool {
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code == http.StatusTooManyRequests {
+		return true
+	}
+
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) && (rpcErr.Code == rpcLimitExceeded || rpcErr.Code == http.StatusTooManyRequests) {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "rate limit") || strings.Contains(message, "too many requests")
+}
+
+// Pull the wait time out of a rate limit error if the server told us one
+func retryAfter(err error) (time.Duration, bool) {
+	match := retryAfterPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0, false
+	}
+
+	seconds, convErr := strconv.Atoi(match[1])
+	if convErr != nil {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// The error the JSON-RPC client returns for an HTTP status, built by a real round trip since its fields are private
+func httpError(t *testing.T, code int) error {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(code), code)
+	}))
+	defer server.Close()
+
+	_, err := jsonrpc.NewClient(server.URL).Call(context.Background(), "eth_blockNumber")
+	var httpErr *jsonrpc.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Code != code {
+		t.Fatalf("got %v, want HTTP %d", err, code)
+	}
+	return err
+}
+
+func TestIsRateLimited(t *testing.T) {
+	throttled := httpError(t, http.StatusTooManyRequests)
+
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{throttled, true},
+		{fmt.Errorf("block 7: %w", throttled), true},
+		{&jsonrpc.RPCError{Code: rpcLimitExceeded, Message: "limit exceeded"}, true},
+		{errors.New("daily Rate Limit reached"), true},
+		{httpError(t, http.StatusBadGateway), false},
+		{errors.New("connection reset by peer"), false},
+	} {
+		if got := IsRateLimited(tc.err); got != tc.want {
+			t.Errorf("IsRateLimited(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if wait, ok := retryAfter(errors.New("too many requests, Retry-After: 3")); !ok || wait != 3*time.Second {
+		t.Errorf("got %s, %v", wait, ok)
+	}
+	if _, ok := retryAfter(errors.New("too many requests")); ok {
+		t.Error("found a wait in an error without one")
+	}
+}
+
+// Answers the first throttled calls with err, then like fakeChain
+type throttledChain struct {
+	fakeChain
+	throttled atomic.Int32
+	err       error
+}
+
+func (c *throttledChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	if c.throttled.Add(-1) >= 0 {
+		return nil, c.err
+	}
+	return c.fakeChain.Call(ctx, method, params...)
+}
+
+func TestThrottledCallsBackOff(t *testing.T) {
+	chain := &throttledChain{err: httpError(t, http.StatusTooManyRequests)}
+	chain.throttled.Store(2)
+
+	var logs bytes.Buffer
+	config := Config{
+		Workers:        1,
+		Retries:        3,
+		RetryDelay:     time.Hour,
+		RateLimitDelay: time.Millisecond,
+		Logger:         slog.New(slog.NewTextHandler(&logs, nil)),
+	}
+
+	// A plain retry would wait the hour of RetryDelay, so finishing at all means the rate limit delay was used
+	result := scan(t, chain, 1, 1, config)
+
+	if len(result.Failed) != 0 {
+		t.Fatalf("failed %v", result.Failed)
+	}
+	if got := strings.Count(logs.String(), "rate limited by the RPC endpoint"); got != 2 {
+		t.Errorf("logged %d rate limit warnings, want 2\n%s", got, logs.String())
+	}
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ofen/getblock-go/eth"
+)
+
+// Receipt is the subset of a transaction receipt the parser cares about
+type Receipt struct {
+	TransactionHash   string   `json:"transactionHash"`
+	From              string   `json:"from"`
+	To                string   `json:"to"`
+	GasUsed           *big.Int `json:"gasUsed"`
+	EffectiveGasPrice *big.Int `json:"effectiveGasPrice"`
+	Logs              []Log    `json:"logs"`
+}
+
+// Log is a single event emitted by a transaction
+type Log struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+}
+
+func (r *Receipt) UnmarshalJSON(data []byte) error {
+	type alias Receipt
+
+	aux := &struct {
+		GasUsed           string `json:"gasUsed"`
+		EffectiveGasPrice string `json:"effectiveGasPrice"`
+		*alias
+	}{
+		alias: (*alias)(r),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if r.GasUsed, err = hexToBig(aux.GasUsed); err != nil {
+		return err
+	}
+	if r.EffectiveGasPrice, err = hexToBig(aux.EffectiveGasPrice); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// The eth package leaves eth_getTransactionReceipt unimplemented, so we call it through the raw JSON-RPC client
+func getTransactionReceipt(ctx context.Context, client Client, hash string) (*Receipt, error) {
+	r, err := client.Call(ctx, "eth_getTransactionReceipt", hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	// Receipts for unknown or pending transactions come back as null
+	if r.Result == nil {
+		return nil, fmt.Errorf("no receipt for transaction %s", hash)
+	}
+
+	receipt := &Receipt{}
+	err = r.GetObject(receipt)
+
+	return receipt, err
+}
+
+// Calculate the fee the sender paid for a transaction
+// Post-London receipts carry the effective gas price, older ones only have the price on the transaction
+func gasFee(tx eth.Transaction, receipt *Receipt) *big.Int {
+	price := receipt.EffectiveGasPrice
+	if price == nil || price.Sign() == 0 {
+		price = tx.GasPrice
+	}
+
+	if price == nil || receipt.GasUsed == nil {
+		return new(big.Int)
+	}
+
+	return new(big.Int).Mul(receipt.GasUsed, price)
+}
+
+// Parse a 0x prefixed hex quantity, empty strings are treated as zero
+func hexToBig(s string) (*big.Int, error) {
+	i := new(big.Int)
+	if s == "" {
+		return i, nil
+	}
+
+	if _, ok := i.SetString(s, 0); !ok {
+		return nil, fmt.Errorf("invalid hex quantity: %s", s)
+	}
+
+	return i, nil
+}
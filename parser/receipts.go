@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+)
+
+// A receipt to fetch on the receipt pool, the answer goes to reply
+type receiptJob struct {
+	ctx   context.Context
+	hash  string
+	reply chan<- receiptReply
+}
+
+type receiptReply struct {
+	hash    string
+	receipt *Receipt
+	err     error
+}
+
+// Receipt pool worker, runs until the jobs channel is closed
+func (s *scanner) receiptWorker(jobs chan receiptJob) {
+	for job := range jobs {
+		receipt, err := s.fetchReceipt(job.ctx, job.hash)
+		if err == nil && receipt == nil {
+			err = fmt.Errorf("no receipt for transaction %s", job.hash)
+		}
+
+		job.reply <- receiptReply{hash: job.hash, receipt: receipt, err: err}
+	}
+}
+
+// Fetch the receipts of a block's transactions on the receipt pool and join them back by hash
+// Every job is waited for even after a failure, so no worker is left writing to a reply nobody reads
+func (s *scanner) fetchReceipts(ctx context.Context, hashes []string) (map[string]*Receipt, error) {
+	reply := make(chan receiptReply, len(hashes))
+	for _, hash := range hashes {
+		s.receiptJobs <- receiptJob{ctx: ctx, hash: hash, reply: reply}
+	}
+
+	receipts := make(map[string]*Receipt, len(hashes))
+	var err error
+
+	for range hashes {
+		r := <-reply
+		if r.err != nil && err == nil {
+			err = r.err
+		}
+		receipts[r.hash] = r.receipt
+	}
+
+	return receipts, err
+}
@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// Holds every receipt call for a moment and remembers how many were in flight at once
+type slowReceipts struct {
+	fakeChain
+	inFlight, most atomic.Int32
+	receipts       atomic.Int32
+}
+
+func (c *slowReceipts) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	if method == "eth_getTransactionReceipt" {
+		c.receipts.Add(1)
+		now := c.inFlight.Add(1)
+		defer c.inFlight.Add(-1)
+		for {
+			most := c.most.Load()
+			if now <= most || c.most.CompareAndSwap(most, now) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return c.fakeChain.Call(ctx, method, params...)
+}
+
+func TestReceiptPoolJoinsReceiptsByHash(t *testing.T) {
+	// Twenty senders each paying a different amount of gas, so a receipt joined to the wrong transaction shows
+	txs := []fakeTx{}
+	for i := 1; i <= 20; i++ {
+		txs = append(txs, fakeTx{from: fmt.Sprintf("0x%040x", 0x100+i), to: bob, value: 1, gasUsed: int64(i), gasPrice: 1})
+	}
+	chain := &slowReceipts{fakeChain: fakeChain{txs: map[uint64][]fakeTx{1: txs}}}
+
+	result := scan(t, chain, 1, 1, Config{Workers: 1, ReceiptWorkers: 4, IncludeGas: true})
+
+	want := map[string]int64{bob: 20}
+	for i := 1; i <= 20; i++ {
+		want[fmt.Sprintf("0x%040x", 0x100+i)] = -1 - int64(i)
+	}
+	wantBalances(t, result, want)
+
+	if got := chain.receipts.Load(); got != 20 {
+		t.Errorf("fetched %d receipts, want 20", got)
+	}
+	// One block worker, so anything above one at a time came from the receipt pool
+	if got := chain.most.Load(); got < 2 || got > 4 {
+		t.Errorf("%d receipts in flight at once, want between 2 and the 4 receipt workers", got)
+	}
+}
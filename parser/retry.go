@@ -0,0 +1,19 @@
+package parser
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Exponential backoff with jitter: somewhere between half and the full base * 2^attempt
+// The jitter keeps workers that failed together from retrying in lockstep
+func backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	delay := base << attempt
+	half := delay / 2
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDoublesWithJitter(t *testing.T) {
+	for attempt := 0; attempt < 5; attempt++ {
+		full := 10 * time.Millisecond << attempt
+		for i := 0; i < 100; i++ {
+			if delay := backoff(10*time.Millisecond, attempt); delay < full/2 || delay > full {
+				t.Fatalf("attempt %d waited %s, want between %s and %s", attempt, delay, full/2, full)
+			}
+		}
+	}
+
+	if delay := backoff(0, 3); delay != 0 {
+		t.Errorf("no base delay waited %s", delay)
+	}
+}
+
+func TestScanRetriesUntilTheBlockIsParsed(t *testing.T) {
+	chain := &fakeChain{failures: map[uint64]int{1: 2}}
+
+	result := scan(t, chain, 1, 1, Config{Workers: 1, Retries: 3, RetryDelay: time.Millisecond})
+
+	if len(result.Failed) != 0 {
+		t.Fatalf("failed %v", result.Failed)
+	}
+	wantBalances(t, result, map[string]int64{alice: -1, bob: 1})
+	if got := chain.fetched(1); got != 3 {
+		t.Errorf("block fetched %d times, want two failures and a success", got)
+	}
+}
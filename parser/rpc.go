@@ -0,0 +1,340 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	getblock "github.com/ofen/getblock-go"
+	"github.com/ofen/getblock-go/eth"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// Client is the JSON-RPC transport a scan talks to
+// Every node call goes through Call, so *getblock.Client works as is and tests can answer with canned responses
+type Client interface {
+	Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error)
+}
+
+// Batcher is implemented by clients that can send several calls in one HTTP request
+// Config.BatchSize only takes effect with one, *getblock.Client is unwrapped to its JSON-RPC client for it
+type Batcher interface {
+	CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error)
+}
+
+// The batching side of a client, nil when it can't send batches
+func batcherOf(client Client) Batcher {
+	switch client := client.(type) {
+	case Batcher:
+		return client
+	case *getblock.Client:
+		return client.Client
+	}
+
+	return nil
+}
+
+// The batching side of the scan's client, nil when it can't send batches
+func (s *scanner) batcher() Batcher {
+	return s.batch
+}
+
+// GetBlock bills every call, so the scanner counts them on the way out
+// Each request inside a batch counts as a call of its own, and so does every retry
+type countingClient struct {
+	client Client
+	calls  *atomic.Int64
+}
+
+func (c countingClient) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	c.calls.Add(1)
+	return c.client.Call(ctx, method, params...)
+}
+
+type countingBatcher struct {
+	batcher Batcher
+	calls   *atomic.Int64
+}
+
+func (b countingBatcher) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	b.calls.Add(int64(len(requests)))
+	return b.batcher.CallBatch(ctx, requests)
+}
+
+// A block of a batch, or why it could not be fetched
+type fetchedBlock struct {
+	block *eth.Block
+	err   error
+}
+
+// Fetch a single block, from the cache if we have it, otherwise over RPC retrying transient failures
+func (s *scanner) fetchBlock(ctx context.Context, blockNum *big.Int) (*eth.Block, error) {
+	if raw, ok := s.cache.get(blockNum); ok {
+		if block, err := decodeBlock(raw); err == nil {
+			return block, nil
+		}
+	}
+
+	var raw []byte
+
+	err := s.call(ctx, func(ctx context.Context) error {
+		var err error
+		raw, err = getBlockByNumber(ctx, s.client, blockNum, s.fullTransactions())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.storeBlock(blockNum, raw)
+}
+
+// Fetch several blocks in a single batch request, cached ones are left out of it
+// Entries the node answered with an error are requested again on their own retry, the rest of the batch is kept
+// The result lines up with blockNums
+func (s *scanner) fetchBlocks(ctx context.Context, blockNums []*big.Int) []fetchedBlock {
+	fetched := make([]fetchedBlock, len(blockNums))
+	pending := []int{}
+
+	for i, blockNum := range blockNums {
+		if raw, ok := s.cache.get(blockNum); ok {
+			if block, err := decodeBlock(raw); err == nil {
+				fetched[i].block = block
+				continue
+			}
+		}
+
+		pending = append(pending, i)
+	}
+
+	if len(pending) == 0 {
+		return fetched
+	}
+
+	err := s.call(ctx, func(ctx context.Context) error {
+		requests := make(jsonrpc.RPCRequests, len(pending))
+		for j, i := range pending {
+			requests[j] = jsonrpc.NewRequest("eth_getBlockByNumber", fmt.Sprintf("%#x", blockNums[i]), s.fullTransactions())
+		}
+
+		// CallBatch numbers the requests by their position, so the IDs map the answers back
+		responses, err := s.batcher().CallBatch(ctx, requests)
+		if err != nil {
+			return err
+		}
+		answers := responses.AsMap()
+
+		failed := []int{}
+		var lastErr error
+
+		for j, i := range pending {
+			raw, err := blockFromResponse(answers[j], blockNums[i])
+			if err == nil {
+				fetched[i].block, err = s.storeBlock(blockNums[i], raw)
+			}
+			if err != nil {
+				failed = append(failed, i)
+				lastErr = err
+			}
+		}
+
+		pending = failed
+		return lastErr
+	})
+
+	for _, i := range pending {
+		fetched[i].err = err
+	}
+
+	return fetched
+}
+
+// Decode a block fetched over RPC and cache it if it is final
+func (s *scanner) storeBlock(blockNum *big.Int, raw []byte) (*eth.Block, error) {
+	block, err := decodeBlock(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blocks near the head may still be reorged away, so only cache the ones deep enough to be final
+	// A block with only transaction hashes would be useless to the next full scan, so those are never cached
+	if s.fullTransactions() && s.config.CacheFinalized != nil && blockNum.Cmp(s.config.CacheFinalized) <= 0 {
+		if err := s.cache.put(blockNum, raw); err != nil {
+			s.log.Warn("cannot cache block", "block", blockNum, "err", err)
+		}
+	}
+
+	return block, nil
+}
+
+// Fetch a single receipt, retrying transient failures
+func (s *scanner) fetchReceipt(ctx context.Context, hash string) (*Receipt, error) {
+	var receipt *Receipt
+
+	err := s.call(ctx, func(ctx context.Context) error {
+		var err error
+		receipt, err = getTransactionReceipt(ctx, s.client, hash)
+		return err
+	})
+
+	return receipt, err
+}
+
+// Run a single RPC call until it succeeds, the retries are used up, or the scan is cancelled
+// Every attempt waits for the rate limiter and the circuit breaker, and gets its own per-request timeout
+// The wait between attempts doubles with some jitter
+// Cancelling ctx ends every wait at once, but a request that already went out runs to its answer, its timeout or the deadline of ctx
+func (s *scanner) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		if err := s.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		// Nothing may come between the breaker letting a call through and recording its outcome
+		if err := s.breaker.wait(ctx); err != nil {
+			return err
+		}
+
+		callCtx, cancel := rpcContext(ctx, s.config)
+		start := time.Now()
+		err = fn(callCtx)
+		s.config.Metrics.rpc(time.Since(start))
+		cancel()
+		s.breaker.record(err)
+
+		if err == nil || attempt >= s.config.Retries {
+			return err
+		}
+
+		// There were retries left, so the block was cut short by the cancellation rather than failed
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w, last attempt failed: %v", ctx.Err(), err)
+		}
+
+		s.config.Metrics.retry()
+
+		// Being throttled is not a bug, so back off for longer and tell the user why
+		delay := backoff(s.config.RetryDelay, attempt)
+		if IsRateLimited(err) {
+			delay = backoff(s.config.RateLimitDelay, attempt)
+			if wait, ok := retryAfter(err); ok && wait > delay {
+				delay = wait
+			}
+
+			s.log.Warn("rate limited by the RPC endpoint, backing off", "delay", delay.Round(time.Millisecond))
+		} else {
+			s.log.Debug("retrying RPC call", "attempt", attempt+1, "delay", delay.Round(time.Millisecond), "err", err)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("%w, last attempt failed: %v", ctx.Err(), err)
+		}
+	}
+}
+
+// Whether err only means the scan was cancelled while the call was waiting
+func stoppedBy(ctx context.Context, err error) bool {
+	return ctx.Err() != nil && errors.Is(err, ctx.Err())
+}
+
+// The eth package ignores JSON-RPC level errors and decodes them as a nil block
+// We make the call ourselves so throttling and other node errors are not mistaken for missing blocks
+// The raw JSON is returned so it can be cached exactly as the node sent it
+// Without full the node only lists the transaction hashes
+func getBlockByNumber(ctx context.Context, client Client, blockNumber *big.Int, full bool) ([]byte, error) {
+	r, err := client.Call(ctx, "eth_getBlockByNumber", fmt.Sprintf("%#x", blockNumber), full)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockFromResponse(r, blockNumber)
+}
+
+// The raw block in one eth_getBlockByNumber answer, a batch may leave an entry out entirely
+func blockFromResponse(r *jsonrpc.RPCResponse, blockNumber *big.Int) ([]byte, error) {
+	if r == nil {
+		return nil, fmt.Errorf("no response for block %s", blockNumber)
+	}
+
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	// The RPC answers null for blocks it doesn't have yet
+	if r.Result == nil {
+		return nil, fmt.Errorf("block %s not found", blockNumber)
+	}
+
+	return json.Marshal(r.Result)
+}
+
+func decodeBlock(raw []byte) (*eth.Block, error) {
+	block := &eth.Block{}
+
+	// The eth package decodes a missing base fee as zero, which some chains really charge
+	// so look at the field itself to tell pre-London blocks apart
+	var fields struct {
+		BaseFeePerGas *string           `json:"baseFeePerGas"`
+		Transactions  []json.RawMessage `json:"transactions"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return block, err
+	}
+
+	// A block fetched without full transactions lists plain hashes, which the eth package can't decode
+	var hashes []string
+	if len(fields.Transactions) > 0 && fields.Transactions[0][0] == '"' {
+		var (
+			object map[string]json.RawMessage
+			err    error
+		)
+		if err = json.Unmarshal(raw, &object); err == nil {
+			err = json.Unmarshal(object["transactions"], &hashes)
+		}
+		if err != nil {
+			return block, err
+		}
+
+		delete(object, "transactions")
+		if raw, err = json.Marshal(object); err != nil {
+			return block, err
+		}
+	}
+
+	if err := json.Unmarshal(raw, block); err != nil {
+		return block, err
+	}
+	for _, hash := range hashes {
+		block.Transactions = append(block.Transactions, eth.Transaction{Hash: hash})
+	}
+
+	if fields.BaseFeePerGas == nil {
+		block.BaseFeePerGas = nil
+	}
+
+	return block, nil
+}
+
+// Derive the context for a single RPC call from the scan context
+// Cancelling the scan leaves the call running, but a deadline on the scan, like -max-duration, still ends it
+func rpcContext(ctx context.Context, config Config) (context.Context, context.CancelFunc) {
+	parent := context.WithoutCancel(ctx)
+
+	// Whichever ends first, the scan's deadline or the per-request timeout
+	if deadline, ok := ctx.Deadline(); ok && (config.RPCTimeout <= 0 || time.Until(deadline) < config.RPCTimeout) {
+		return context.WithDeadline(parent, deadline)
+	}
+	if config.RPCTimeout <= 0 {
+		return context.WithCancel(parent)
+	}
+
+	return context.WithTimeout(parent, config.RPCTimeout)
+}
@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+func TestCallTimesOutSlowRequests(t *testing.T) {
+	// The node never answers, only the per-request timeout ends the call
+	chain := &fakeChain{release: make(chan struct{})}
+	s := newScanner(chain, Config{Workers: 1, RPCTimeout: 20 * time.Millisecond})
+
+	start := time.Now()
+	_, err := s.fetchBlock(context.Background(), big.NewInt(1))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want a deadline error", err)
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Errorf("call took %s with a 20ms timeout", took)
+	}
+}
+
+func TestTimedOutBlocksAreFailed(t *testing.T) {
+	chain := &fakeChain{release: make(chan struct{})}
+
+	result := scan(t, chain, 1, 2, Config{Workers: 2, RPCTimeout: 20 * time.Millisecond})
+
+	// The scan itself was never cancelled, so these are real failures
+	if len(result.Failed) != 2 {
+		t.Errorf("failed %v, want both blocks", result.Failed)
+	}
+}
+
+func TestRPSSpacesOutCalls(t *testing.T) {
+	chain := &fakeChain{}
+
+	// The limiter lets the first call through at once and one more every 20ms
+	start := time.Now()
+	result := scan(t, chain, 1, 10, Config{Workers: 4, RPS: 50})
+	if took := time.Since(start); took < 9*20*time.Millisecond {
+		t.Errorf("10 calls at 50 per second took %s, want at least 180ms", took)
+	}
+	if result.Calls != 10 {
+		t.Errorf("calls %d", result.Calls)
+	}
+}
+
+// Answers batches entry by entry from the fake chain, an entry that fails becomes an error in its slot
+type batchingChain struct {
+	fakeChain
+
+	mu      sync.Mutex
+	batches []int
+}
+
+func (c *batchingChain) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	c.mu.Lock()
+	c.batches = append(c.batches, len(requests))
+	c.mu.Unlock()
+
+	responses := jsonrpc.RPCResponses{}
+	for i, request := range requests {
+		r, err := c.fakeChain.Call(ctx, request.Method, request.Params.([]interface{})...)
+		if err != nil {
+			r = &jsonrpc.RPCResponse{Error: &jsonrpc.RPCError{Code: -32000, Message: err.Error()}}
+		}
+		r.ID = i
+		responses = append(responses, r)
+	}
+	return responses, nil
+}
+
+func TestBatchesFetchSeveralBlocksAtOnce(t *testing.T) {
+	// Block 2 fails its first try, only that one goes out again
+	chain := &batchingChain{fakeChain: fakeChain{failures: map[uint64]int{2: 1}}}
+
+	result := scan(t, chain, 1, 8, Config{Workers: 1, BatchSize: 4, Retries: 1, RetryDelay: time.Millisecond})
+
+	wantBalances(t, result, map[string]int64{alice: -36, bob: 36})
+	if got := fmt.Sprint(chain.batches); got != "[4 1 4]" {
+		t.Errorf("sent batches of %s, want [4 1 4]", got)
+	}
+	for n := uint64(1); n <= 8; n++ {
+		want := 1
+		if n == 2 {
+			want = 2
+		}
+		if got := chain.fetched(n); got != want {
+			t.Errorf("block %d fetched %d times, want %d", n, got, want)
+		}
+	}
+
+	// Every request of a batch is billed on its own
+	if result.Calls != 9 {
+		t.Errorf("counted %d calls, want 9", result.Calls)
+	}
+}
+
+// A missing base fee is a pre-London block, a zero one is a chain that charges nothing
+func TestDecodeBlockTellsMissingBaseFeeFromZero(t *testing.T) {
+	for raw, want := range map[string]string{
+		`{"number": "0x1", "transactions": []}`:                                "<nil>",
+		`{"number": "0x1", "baseFeePerGas": "0x0", "transactions": []}`:        "0",
+		`{"number": "0x1", "baseFeePerGas": "0x3b9aca00", "transactions": []}`: "1000000000",
+	} {
+		block, err := decodeBlock([]byte(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(block.BaseFeePerGas); got != want {
+			t.Errorf("%s has base fee %s, want %s", raw, got, want)
+		}
+	}
+}
+
+// Answers like a node does when blocks are asked for without full transactions, and remembers which were asked for
+type detailChain struct {
+	fakeChain
+
+	mu   sync.Mutex
+	full []bool
+}
+
+func (c *detailChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	r, err := c.fakeChain.Call(ctx, method, params...)
+	if err != nil || method != "eth_getBlockByNumber" {
+		return r, err
+	}
+
+	full := params[1].(bool)
+	c.mu.Lock()
+	c.full = append(c.full, full)
+	c.mu.Unlock()
+
+	if !full {
+		block := r.Result.(map[string]interface{})
+		hashes := []string{}
+		for _, tx := range block["transactions"].([]interface{}) {
+			hashes = append(hashes, tx.(map[string]interface{})["hash"].(string))
+		}
+		block["transactions"] = hashes
+	}
+	return r, nil
+}
+
+func TestTxCountsOnlyFetchesHashes(t *testing.T) {
+	chain := &detailChain{fakeChain: fakeChain{txs: map[uint64][]fakeTx{
+		2: {{from: alice, to: bob, value: 1}, {from: bob, to: carol, value: 2}, {from: carol, to: alice, value: 3}},
+	}}}
+
+	result := scan(t, chain, 1, 3, Config{Workers: 2, TxCountsOnly: true})
+	if result.Stats.Transactions != 5 {
+		t.Errorf("counted %d transactions, want 5", result.Stats.Transactions)
+	}
+	for _, full := range chain.full {
+		if full {
+			t.Fatalf("full transactions asked for, got %v", chain.full)
+		}
+	}
+	if len(chain.full) != 3 {
+		t.Errorf("%d blocks fetched, want 3", len(chain.full))
+	}
+}
+
+func TestSettingsThatReadValuesFetchFullTransactions(t *testing.T) {
+	for name, config := range map[string]Config{
+		"default":  {},
+		"gas":      {TxCountsOnly: true, IncludeGas: true},
+		"tokens":   {TxCountsOnly: true, Tokens: true},
+		"trace":    {TxCountsOnly: true, Trace: true},
+		"minimum":  {TxCountsOnly: true, TxMinWei: big.NewInt(1)},
+		"between":  {TxCountsOnly: true, Between: []string{alice, bob}},
+		"gasstats": {TxCountsOnly: true, GasStats: true},
+	} {
+		if !newScanner(&fakeChain{}, config).fullTransactions() {
+			t.Errorf("%s fetches hashes only", name)
+		}
+	}
+
+	// A zero minimum keeps every transaction, there is no value to look at
+	if newScanner(&fakeChain{}, Config{TxCountsOnly: true, TxMinWei: new(big.Int)}).fullTransactions() {
+		t.Error("a zero -tx-min-wei fetches full transactions")
+	}
+}
@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Topic hash of the Transfer(address,address,uint256) event, shared by ERC-20 and ERC-721
+const transferTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// TokenHolder identifies one holder in one token contract, an ERC-20 token or an ERC-721 collection
+type TokenHolder struct {
+	Token  string
+	Holder string
+}
+
+// TokenChange is a single signed change to a holder's token balance, in the token's smallest unit
+type TokenChange struct {
+	TokenHolder
+	Amount big.Int
+}
+
+// Decode an ERC-20 Transfer log into the debit of the sender and the credit of the receiver
+// The standard layout indexes from and to as topics and leaves the amount in data
+// Some early tokens indexed nothing and packed all three values into data, so we accept both
+// ERC-721 transfers index the token id as a fourth topic and are not token amounts, decodeNFTTransfer handles those
+func decodeTransfer(log Log) ([]TokenChange, bool) {
+	if len(log.Topics) == 0 || !strings.EqualFold(log.Topics[0], transferTopic) {
+		return nil, false
+	}
+
+	data := strings.TrimPrefix(log.Data, "0x")
+
+	var from, to, amount string
+	switch {
+	case len(log.Topics) == 3 && len(data) == 64:
+		from, to, amount = log.Topics[1], log.Topics[2], data
+	case len(log.Topics) == 1 && len(data) == 192:
+		from, to, amount = data[:64], data[64:128], data[128:]
+	default:
+		return nil, false
+	}
+
+	value, ok := new(big.Int).SetString(amount, 16)
+	if !ok {
+		return nil, false
+	}
+
+	token := NormalizeAddress(log.Address)
+	sent := TokenChange{TokenHolder: TokenHolder{Token: token, Holder: wordToAddress(from)}}
+	sent.Amount.Neg(value)
+	received := TokenChange{TokenHolder: TokenHolder{Token: token, Holder: wordToAddress(to)}}
+	received.Amount.Set(value)
+
+	return []TokenChange{sent, received}, true
+}
+
+// Addresses are left padded to a full 32 byte word in topics and data, keep the last 20 bytes
+func wordToAddress(word string) string {
+	word = strings.TrimPrefix(word, "0x")
+	if len(word) < 40 {
+		return "0x" + strings.ToLower(word)
+	}
+
+	return "0x" + strings.ToLower(word[len(word)-40:])
+}
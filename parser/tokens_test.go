@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+const usdc = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+
+// An address or number as a 32 byte word
+func word(value string) string {
+	return fmt.Sprintf("%064s", strings.TrimPrefix(value, "0x"))
+}
+
+func TestDecodeTransfer(t *testing.T) {
+	log := Log{
+		Address: strings.ToUpper(usdc[:2]) + usdc[2:],
+		Topics:  []string{transferTopic, "0x" + word(alice), "0x" + word(bob)},
+		Data:    "0x" + word("f4240"),
+	}
+
+	changes, ok := decodeTransfer(log)
+	if !ok || len(changes) != 2 {
+		t.Fatalf("got %v, %v", changes, ok)
+	}
+
+	sent, received := changes[0], changes[1]
+	if sent.Token != usdc || sent.Holder != alice || sent.Amount.Int64() != -1000000 {
+		t.Errorf("sent %+v", sent)
+	}
+	if received.Token != usdc || received.Holder != bob || received.Amount.Int64() != 1000000 {
+		t.Errorf("received %+v", received)
+	}
+}
+
+func TestDecodeTransferWithNothingIndexed(t *testing.T) {
+	log := Log{Address: usdc, Topics: []string{transferTopic}, Data: "0x" + word(alice) + word(bob) + word("2a")}
+
+	changes, ok := decodeTransfer(log)
+	if !ok || changes[0].Holder != alice || changes[1].Holder != bob || changes[1].Amount.Int64() != 42 {
+		t.Fatalf("got %v, %v", changes, ok)
+	}
+}
+
+func TestDecodeTransferIgnoresOtherLogs(t *testing.T) {
+	for name, log := range map[string]Log{
+		"approval": {Address: usdc, Topics: []string{"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925", "0x" + word(alice), "0x" + word(bob)}, Data: "0x" + word("1")},
+		"erc-721":  {Address: usdc, Topics: []string{transferTopic, "0x" + word(alice), "0x" + word(bob), "0x" + word("7")}, Data: "0x"},
+		"no topic": {Address: usdc, Data: "0x" + word("1")},
+	} {
+		if changes, ok := decodeTransfer(log); ok {
+			t.Errorf("%s decoded as %v", name, changes)
+		}
+	}
+}
+
+// Answers eth_call like USDC does, decimals() with 6 and symbol() with "USDC"
+type tokenContract struct {
+	calls atomic.Int32
+}
+
+func (c *tokenContract) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	if method != "eth_call" {
+		return nil, fmt.Errorf("unexpected %s call", method)
+	}
+	c.calls.Add(1)
+
+	switch params[0].(map[string]string)["data"] {
+	case decimalsSelector:
+		return &jsonrpc.RPCResponse{Result: "0x" + word("6")}, nil
+	case symbolSelector:
+		return &jsonrpc.RPCResponse{Result: "0x" + word("20") + word("4") + hex.EncodeToString([]byte("USDC")) + strings.Repeat("0", 56)}, nil
+	}
+	return &jsonrpc.RPCResponse{Error: &jsonrpc.RPCError{Code: -32000, Message: "execution reverted"}}, nil
+}
+
+func TestTokenInfo(t *testing.T) {
+	contract := &tokenContract{}
+	s := newScanner(contract, Config{Workers: 1})
+
+	info := s.tokenInfo(context.Background(), usdc)
+	if info.Symbol != "USDC" || info.Decimals != 6 {
+		t.Fatalf("got %+v", info)
+	}
+
+	// Looked up once per scan however many transfers the token has
+	s.tokenInfo(context.Background(), usdc)
+	if got := contract.calls.Load(); got != 2 {
+		t.Errorf("%d eth_call requests, want 2", got)
+	}
+}
+
+func TestDecodeStringAcceptsBytes32(t *testing.T) {
+	data := make([]byte, 32)
+	copy(data, "MKR")
+
+	if symbol, err := decodeString(data); err != nil || symbol != "MKR" {
+		t.Errorf("got %q, %v", symbol, err)
+	}
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// JSON-RPC error code for a method the node doesn't implement
+const rpcMethodNotFound = -32601
+
+// One call frame of geth's callTracer, calls holds the frames it made in turn
+type callFrame struct {
+	Type  string      `json:"type"`
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Value string      `json:"value"`
+	Error string      `json:"error"`
+	Calls []callFrame `json:"calls"`
+}
+
+// debug_traceBlockByNumber answers with one entry per transaction, in block order
+type traceResult struct {
+	Result callFrame `json:"result"`
+}
+
+func traceBlock(ctx context.Context, client Client, blockNumber *big.Int) ([]traceResult, error) {
+	r, err := client.Call(ctx, "debug_traceBlockByNumber", fmt.Sprintf("%#x", blockNumber), map[string]string{"tracer": "callTracer"})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	raw, err := json.Marshal(r.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	traces := []traceResult{}
+	err = json.Unmarshal(raw, &traces)
+
+	return traces, err
+}
+
+// Fetch the traces of a block, retrying transient failures
+// False means the endpoint can't trace, after the first such answer no further blocks are traced
+func (s *scanner) fetchTraces(ctx context.Context, blockNum *big.Int) ([]traceResult, bool, error) {
+	if s.traceUnsupported.Load() {
+		return nil, false, nil
+	}
+
+	var traces []traceResult
+	var unsupported error
+
+	err := s.call(ctx, func(ctx context.Context) error {
+		var err error
+		traces, err = traceBlock(ctx, s.client, blockNum)
+
+		// Asking again won't make the method appear, so don't spend retries on it
+		if isUnsupported(err) {
+			unsupported = err
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if unsupported != nil {
+		if s.traceUnsupported.CompareAndSwap(false, true) {
+			s.log.Warn("the endpoint does not support debug_traceBlockByNumber, internal transfers are not counted", "err", unsupported)
+		}
+		return nil, false, nil
+	}
+
+	return traces, true, nil
+}
+
+// Whether err says the node lacks the method, rather than failing to run it
+func isUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == rpcMethodNotFound {
+		return true
+	}
+
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "not supported") || strings.Contains(message, "does not exist") || strings.Contains(message, "not available")
+}
+
+// Turn the value moved by calls inside a transaction into balance changes
+// The top frame is the transaction itself, its value is already counted from the block
+// Frames that reverted moved nothing, and neither did anything they called
+func internalTransfers(frame callFrame) []BalanceChange {
+	changes := []BalanceChange{}
+
+	var walk func(frames []callFrame)
+	walk = func(frames []callFrame) {
+		for _, call := range frames {
+			if call.Error != "" {
+				continue
+			}
+
+			// Delegate and static calls can't move value of their own
+			kind := strings.ToUpper(call.Type)
+			if kind != "DELEGATECALL" && kind != "STATICCALL" {
+				value, err := hexToBig(call.Value)
+				if err == nil && value.Sign() > 0 {
+					from, to := NormalizeAddress(call.From), NormalizeAddress(call.To)
+					sent := new(big.Int).Neg(value)
+
+					changes = append(changes, BalanceChange{Address: from, Balance: *sent}, BalanceChange{Address: to, Balance: *value})
+				}
+			}
+
+			walk(call.Calls)
+		}
+	}
+
+	if frame.Error == "" {
+		walk(frame.Calls)
+	}
+
+	return changes
+}
@@ -0,0 +1,92 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// One transaction from alice into a contract at 0x..0d, which pays bob 3 wei and calls 0x..0e, which pays carol 4 wei
+// A reverted call and a delegate call along the way move nothing
+const syntheticTrace = `[{"result": {
+	"type": "CALL", "from": "0x000000000000000000000000000000000000000A", "to": "0x000000000000000000000000000000000000000d", "value": "0xa",
+	"calls": [
+		{"type": "CALL", "from": "0x000000000000000000000000000000000000000d", "to": "0x000000000000000000000000000000000000000b", "value": "0x3"},
+		{"type": "CALL", "from": "0x000000000000000000000000000000000000000d", "to": "0x000000000000000000000000000000000000000e", "value": "0x0",
+			"calls": [{"type": "CALL", "from": "0x000000000000000000000000000000000000000e", "to": "0x000000000000000000000000000000000000000c", "value": "0x4"}]},
+		{"type": "CALL", "from": "0x000000000000000000000000000000000000000d", "to": "0x000000000000000000000000000000000000000c", "value": "0x9", "error": "execution reverted",
+			"calls": [{"type": "CALL", "from": "0x000000000000000000000000000000000000000c", "to": "0x000000000000000000000000000000000000000b", "value": "0x1"}]},
+		{"type": "DELEGATECALL", "from": "0x000000000000000000000000000000000000000d", "to": "0x000000000000000000000000000000000000000f", "value": "0xa"}
+	]
+}}]`
+
+const contract = "0x000000000000000000000000000000000000000d"
+
+func TestInternalTransfers(t *testing.T) {
+	var traces []traceResult
+	if err := json.Unmarshal([]byte(syntheticTrace), &traces); err != nil {
+		t.Fatal(err)
+	}
+
+	totals := map[string]int64{}
+	for _, change := range internalTransfers(traces[0].Result) {
+		totals[change.Address] += change.Balance.Int64()
+	}
+
+	// The 10 wei of the transaction itself is counted from the block, not here
+	want := map[string]int64{contract: -3, bob: 3, "0x000000000000000000000000000000000000000e": -4, carol: 4}
+	if len(totals) != len(want) {
+		t.Errorf("changes %v, want %v", totals, want)
+	}
+	for address, change := range want {
+		if totals[address] != change {
+			t.Errorf("%s changed by %d, want %d", address, totals[address], change)
+		}
+	}
+}
+
+// Answers debug_traceBlockByNumber with the synthetic trace, or says the method doesn't exist
+type tracingChain struct {
+	fakeChain
+	unsupported bool
+}
+
+func (c *tracingChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	if method != "debug_traceBlockByNumber" {
+		return c.fakeChain.Call(ctx, method, params...)
+	}
+	if c.unsupported {
+		return &jsonrpc.RPCResponse{Error: &jsonrpc.RPCError{Code: rpcMethodNotFound, Message: "the method debug_traceBlockByNumber does not exist/is not available"}}, nil
+	}
+
+	var result interface{}
+	json.Unmarshal([]byte(syntheticTrace), &result)
+	return &jsonrpc.RPCResponse{Result: result}, nil
+}
+
+func TestTraceFoldsInternalTransfers(t *testing.T) {
+	chain := &tracingChain{fakeChain: fakeChain{txs: map[uint64][]fakeTx{1: {{from: alice, to: contract, value: 10}}}}}
+
+	result := scan(t, chain, 1, 1, Config{Workers: 1, Trace: true})
+
+	wantBalances(t, result, map[string]int64{alice: -10, contract: 7, bob: 3, "0x000000000000000000000000000000000000000e": -4, carol: 4})
+}
+
+func TestTraceFallsBackWhenUnsupported(t *testing.T) {
+	chain := &tracingChain{unsupported: true}
+
+	var logs bytes.Buffer
+	config := Config{Workers: 1, Trace: true, Retries: 3, Logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	result := scan(t, chain, 1, 3, config)
+
+	// Plain values are still counted and the warning is given once, not for every block
+	wantBalances(t, result, map[string]int64{alice: -6, bob: 6})
+	if got := strings.Count(logs.String(), "does not support debug_traceBlockByNumber"); got != 1 {
+		t.Errorf("warned %d times\n%s", got, logs.String())
+	}
+}
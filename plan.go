@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Describe what a scan of from..to would do without making any calls
+// Receipts depend on how many transactions the blocks hold, so that part can only be a per transaction figure
+func printPlan(w io.Writer, from, to *big.Int, config parser.Config) error {
+	count := new(big.Int).Sub(to, from)
+	count.Add(count, big.NewInt(1))
+	if count.Sign() < 0 {
+		count.SetInt64(0)
+	}
+
+	fmt.Fprintf(w, "From block:     %d\n", from)
+	fmt.Fprintf(w, "To block:       %d\n", to)
+	fmt.Fprintf(w, "Blocks:         %d\n", count)
+
+	// Only the sampled blocks are fetched
+	if sample := big.NewInt(int64(config.Sample)); config.Sample > 0 && sample.Cmp(count) < 0 {
+		count = sample
+		fmt.Fprintf(w, "Sample:         %d blocks, seed %d\n", count, config.Seed)
+	}
+
+	fmt.Fprintf(w, "Workers:        %d\n", config.Workers)
+
+	// A batch of blocks goes out as one HTTP request
+	requests := new(big.Int).Set(count)
+	if config.BatchSize > 1 {
+		batch := big.NewInt(int64(config.BatchSize))
+		requests.Add(requests, new(big.Int).Sub(batch, big.NewInt(1)))
+		requests.Quo(requests, batch)
+		fmt.Fprintf(w, "Batch size:     %d\n", config.BatchSize)
+	}
+	fmt.Fprintf(w, "Block requests: %d\n", requests)
+
+	if config.IncludeGas || config.Tokens {
+		fmt.Fprintln(w, "Receipts:       one request per transaction on top of the block requests")
+	} else {
+		fmt.Fprintln(w, "Receipts:       none")
+	}
+
+	if config.Trace {
+		fmt.Fprintf(w, "Traces:         %d\n", count)
+	}
+
+	if config.RPS > 0 {
+		seconds := new(big.Float).Quo(new(big.Float).SetInt(requests), big.NewFloat(config.RPS))
+		fmt.Fprintf(w, "Minimum time:   %.0fs at %g requests per second, not counting receipts\n", seconds, config.RPS)
+	}
+
+	return nil
+}
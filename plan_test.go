@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+func TestDryRunFetchesNoBlocks(t *testing.T) {
+	for name, tc := range map[string]struct {
+		from, to int
+		want     []string
+	}{
+		"latest blocks":  {from: -1, to: -1, want: []string{"eth_blockNumber"}},
+		"explicit range": {from: 3, to: 5, want: nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			node, server := newFakeNode(t, 100)
+
+			opts := testOptions(t, server.URL)
+			opts.from, opts.to = tc.from, tc.to
+			opts.dryRun = true
+
+			if err := runParser(context.Background(), opts, parser.Config{Workers: 2}); err != nil {
+				t.Fatal(err)
+			}
+
+			node.mu.Lock()
+			defer node.mu.Unlock()
+			if strings.Join(node.methods, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("called %v, want %v", node.methods, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlanCountsRequests(t *testing.T) {
+	var out bytes.Buffer
+	config := parser.Config{Workers: 4, BatchSize: 10, RPS: 5}
+	if err := printPlan(&out, big.NewInt(100), big.NewInt(124), config); err != nil {
+		t.Fatal(err)
+	}
+
+	// 25 blocks in batches of 10 is 3 requests, at 5 a second that rounds to 1s
+	for _, want := range []string{"Blocks:         25\n", "Workers:        4\n", "Block requests: 3\n", "Receipts:       none\n", "Minimum time:   1s"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("plan is missing %q\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
+)
+
+// Report scan progress on stderr
+// On a terminal this is a live bar with rate and ETA, otherwise a plain line every 10% so logs stay readable
+type progress struct {
+	out   *os.File
+	tty   bool
+	bar   *progressbar.ProgressBar
+	start time.Time
+	step  int
+}
+
+func newProgress(out *os.File) *progress {
+	return &progress{
+		out:   out,
+		tty:   term.IsTerminal(int(out.Fd())),
+		start: time.Now(),
+	}
+}
+
+// Called by the parser after every block, the total is only known once the scan starts
+func (p *progress) update(done, total int) {
+	if p.tty {
+		if p.bar == nil {
+			p.bar = progressbar.NewOptions(total,
+				progressbar.OptionSetWriter(p.out),
+				progressbar.OptionSetDescription("scanning blocks"),
+				progressbar.OptionShowCount(),
+				progressbar.OptionShowIts(),
+				progressbar.OptionSetItsString("blocks"),
+				progressbar.OptionSetPredictTime(true),
+				progressbar.OptionClearOnFinish(),
+			)
+		}
+
+		p.bar.Set(done)
+		return
+	}
+
+	// Only log when we cross into the next 10% step
+	step := done * 10 / total
+	if step == p.step && done != total {
+		return
+	}
+	p.step = step
+
+	elapsed := time.Since(p.start)
+	eta := elapsed / time.Duration(done) * time.Duration(total-done)
+	fmt.Fprintf(p.out, "scanned %d/%d blocks, ETA %s\n", done, total, eta.Round(time.Second))
+}
+
+// Clear the bar so it never ends up mixed into the results
+// Safe to call on a nil progress, which is what we have when progress is disabled
+func (p *progress) finish() {
+	if p == nil || p.bar == nil {
+		return
+	}
+
+	p.bar.Finish()
+	p.bar.Clear()
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// A single row of machine readable output
+// Amounts are strings so big.Int values survive JSON without losing precision
+type jsonResult struct {
+	Address   string `json:"address"`
+	ChangeWei string `json:"change_wei"`
+	ChangeEth string `json:"change_eth"`
+	TxCount   int    `json:"tx_count"`
+	Label     string `json:"label,omitempty"`
+	Name      string `json:"ens_name,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+// Turn the sorted addresses into rows, in EIP-55 checksum form unless the user wants raw lowercase
+// Aggregation always keys off lowercase, this only changes how addresses are displayed
+func buildRows(addresses []string, balances map[string]*big.Int, flows map[string]*parser.Flow, labels map[string]string, checksum bool) []report.Row {
+	rows := make([]report.Row, 0, len(addresses))
+
+	for _, address := range addresses {
+		display := address
+		if checksum {
+			display = parser.ChecksumAddress(address)
+		}
+
+		r := report.Row{Address: display, Change: balances[address], Sent: new(big.Int), Received: new(big.Int), Label: labels[address]}
+		if flow, ok := flows[address]; ok {
+			r.Sent, r.Received, r.TxCount = flow.Sent, flow.Received, flow.Transactions
+		}
+
+		rows = append(rows, r)
+	}
+
+	return rows
+}
+
+// The line shown in place of an empty table
+func noChanges(result *parser.Result) string {
+	switch {
+	case result.From == nil || result.To == nil:
+		return "No balance changes found in the scanned blocks"
+	case result.From.Cmp(result.To) > 0:
+		return "No balance changes found, no blocks were scanned"
+	case result.From.Cmp(result.To) == 0:
+		return fmt.Sprintf("No balance changes found in block %d", result.From)
+	default:
+		return fmt.Sprintf("No balance changes found in blocks %d–%d", result.From, result.To)
+	}
+}
+
+// Render a pretty table with the results, amounts in the unit the user picked
+func renderTable(w io.Writer, rows []report.Row, u report.Unit, columns []tableColumn) {
+	header, records := tableCells(rows, u, columns, 1)
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.AppendBulk(records)
+	table.Render()
+}
+
+// Render the same table as GitHub flavored Markdown, still aligned so the raw text reads well
+func renderMarkdown(w io.Writer, rows []report.Row, u report.Unit, columns []tableColumn) {
+	header, records := tableCells(rows, u, columns, 1)
+
+	// A pipe inside a cell would end it early
+	escape := strings.NewReplacer("|", "\\|")
+	for _, record := range records {
+		for i := range record {
+			record[i] = escape.Replace(record[i])
+		}
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	table.AppendBulk(records)
+	table.Render()
+}
+
+// The header and cells of the results table, ranks start at first
+// Without columns the default set is used
+func tableCells(rows []report.Row, u report.Unit, columns []tableColumn, first int) ([]string, [][]string) {
+	if columns == nil {
+		columns = defaultColumns(rows)
+	}
+
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.header(u))
+	}
+
+	records := make([][]string, 0, len(rows))
+	for i, r := range rows {
+		record := make([]string, 0, len(columns))
+		for _, column := range columns {
+			record = append(record, column.cell(first+i, r, u))
+		}
+
+		records = append(records, record)
+	}
+
+	return header, records
+}
+
+// Write the results as a JSON array, in the same order as the table
+func renderJSON(w io.Writer, rows []report.Row) error {
+	results := make([]jsonResult, 0, len(rows))
+
+	for _, r := range rows {
+		results = append(results, jsonRow(r))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(results)
+}
+
+// Write one JSON object per line, which tools like jq can consume while it is still being written
+func renderNDJSON(w io.Writer, rows []report.Row) error {
+	encoder := json.NewEncoder(w)
+
+	for _, r := range rows {
+		if err := encoder.Encode(jsonRow(r)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func jsonRow(r report.Row) jsonResult {
+	return jsonResult{
+		Address:   r.Address,
+		ChangeWei: r.Change.String(),
+		ChangeEth: exactEther(r.Change),
+		TxCount:   r.TxCount,
+		Label:     r.Label,
+		Name:      r.Name,
+		Type:      r.Kind,
+	}
+}
+
+// Write the results as CSV with a header row, in the same order as the table
+func renderCSV(w io.Writer, rows []report.Row) error {
+	writer := csv.NewWriter(w)
+
+	// The type column only exists with -classify, so existing consumers of the default columns are not affected
+	typed := false
+	for _, r := range rows {
+		typed = typed || r.Kind != ""
+	}
+
+	header := []string{"rank", "address", "change_eth", "change_wei", "tx_count"}
+	if typed {
+		header = append(header, "type")
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for i, r := range rows {
+		record := []string{fmt.Sprintf("%d", i+1), r.Address, exactEther(r.Change), r.Change.String(), fmt.Sprintf("%d", r.TxCount)}
+		if typed {
+			record = append(record, r.Kind)
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	// The csv writer buffers, so make sure everything reaches w before returning
+	writer.Flush()
+
+	return writer.Error()
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// Regenerate the golden files with go test -run Golden -update
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the current output")
+
+// Compare output byte for byte with testdata/name.golden
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run the test with -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// A fixed set of balances covering gains, losses, labels and an amount too small to show at four decimals
+func goldenRows(t *testing.T) []report.Row {
+	t.Helper()
+
+	amount := func(s string) *big.Int {
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			t.Fatalf("bad amount %s", s)
+		}
+		return n
+	}
+
+	return []report.Row{
+		{Address: "0xd8da6bf26964af9d7eed9e03e53415d37aa96045", Change: amount("12345678901234567890"), Sent: amount("0"), Received: amount("12345678901234567890"), TxCount: 3, Label: "vitalik"},
+		{Address: "0x00000000219ab540356cbb839cbe05303d7705fa", Change: amount("1"), Sent: amount("0"), Received: amount("1"), TxCount: 1},
+		{Address: "0x28c6c06298d514db089934071355e5743bf21d60", Change: amount("-12345678901234567891"), Sent: amount("12345678901234567891"), Received: amount("0"), TxCount: 12, Label: "binance"},
+	}
+}
+
+func TestGoldenRenderers(t *testing.T) {
+	u, err := lookupUnit("eth", "ETH", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &parser.Result{From: big.NewInt(100), To: big.NewInt(110), Blocks: 11}
+	info := report.Info{Unit: u, Currency: "ETH", Decimals: 4, Result: result, Took: time.Second}
+
+	for _, format := range []string{"table", "markdown", "json", "csv"} {
+		for name, rows := range map[string][]report.Row{"empty": nil, "rows": goldenRows(t)} {
+			t.Run(format+"_"+name, func(t *testing.T) {
+				registered, ok := report.Lookup(format)
+				if !ok {
+					t.Fatalf("%s is not registered", format)
+				}
+
+				var out bytes.Buffer
+				if err := registered.Renderer.Render(&out, rows, info); err != nil {
+					t.Fatal(err)
+				}
+				golden(t, format+"_"+name, out.Bytes())
+			})
+		}
+	}
+}
+
+// The amounts in the table follow -unit, so wei must come out exact
+func TestGoldenTableInWei(t *testing.T) {
+	u, err := lookupUnit("wei", "ETH", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	renderTable(&out, goldenRows(t), u, nil)
+	golden(t, "table_wei", out.Bytes())
+}
+
+func TestJSONRoundTrips(t *testing.T) {
+	rows := goldenRows(t)
+	sortRows(rows, "net", true)
+
+	var out bytes.Buffer
+	if err := renderJSON(&out, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded []jsonResult
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(rows) {
+		t.Fatalf("got %d rows back, want %d", len(decoded), len(rows))
+	}
+	for i, r := range rows {
+		change, ok := new(big.Int).SetString(decoded[i].ChangeWei, 10)
+		if !ok || decoded[i].Address != r.Address || change.Cmp(r.Change) != 0 || decoded[i].TxCount != r.TxCount || decoded[i].Label != r.Label {
+			t.Errorf("row %d came back as %+v, want %+v", i, decoded[i], r)
+		}
+	}
+}
+
+func TestCSVParsesBack(t *testing.T) {
+	rows := goldenRows(t)
+	sortRows(rows, "net", true)
+
+	var out bytes.Buffer
+	if err := renderCSV(&out, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The header and then the rows in the same order
+	if len(records) != len(rows)+1 || records[0][1] != "address" {
+		t.Fatalf("records %v", records)
+	}
+	for i, r := range rows {
+		record := records[i+1]
+		if record[0] != strconv.Itoa(i+1) || record[1] != r.Address || record[3] != r.Change.String() {
+			t.Errorf("record %d is %v, want %s with %s wei", i+1, record, r.Address, r.Change)
+		}
+	}
+}
+
+// Decode NDJSON output line by line, every line has to be an object of its own
+func ndjsonLines(t *testing.T, output string) []jsonResult {
+	t.Helper()
+
+	var rows []jsonResult
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		var row jsonResult
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("line %d: %v in %q", len(rows)+1, err, scanner.Text())
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestNDJSONParsesBackLineByLine(t *testing.T) {
+	rows := goldenRows(t)
+
+	var out bytes.Buffer
+	if err := renderNDJSON(&out, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := ndjsonLines(t, out.String())
+	if len(decoded) != len(rows) {
+		t.Fatalf("got %d lines, want %d", len(decoded), len(rows))
+	}
+	for i, r := range rows {
+		if decoded[i].Address != r.Address || decoded[i].ChangeWei != r.Change.String() || decoded[i].Label != r.Label {
+			t.Errorf("line %d is %+v, want %+v", i+1, decoded[i], r)
+		}
+	}
+}
+
+// Without a limit the rows stream out unsorted, with one they are ranked like every other format
+func TestNDJSONStreamsEveryAddress(t *testing.T) {
+	balances := mixedBalances(5)
+
+	for name, tc := range map[string]struct {
+		top  int
+		want int
+	}{
+		"streamed": {top: 0, want: 5},
+		"ranked":   {top: 2, want: 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts := options{format: "ndjson", sortKey: "net", descending: true, top: tc.top}
+
+			var out strings.Builder
+			if err := writeResults(context.Background(), &out, opts, nil, &parser.Result{Balances: balances}, time.Second); err != nil {
+				t.Fatal(err)
+			}
+
+			seen := map[string]bool{}
+			for _, row := range ndjsonLines(t, out.String()) {
+				change, ok := new(big.Int).SetString(row.ChangeWei, 10)
+				if !ok || balances[row.Address] == nil || change.Cmp(balances[row.Address]) != 0 || seen[row.Address] {
+					t.Errorf("unexpected line %+v", row)
+				}
+				seen[row.Address] = true
+			}
+			if len(seen) != tc.want {
+				t.Errorf("got %d addresses, want %d", len(seen), tc.want)
+			}
+		})
+	}
+}
+
+// GitHub only renders a table whose second line is the separator, with as many cells as the header
+func TestMarkdownSeparatorAndPipes(t *testing.T) {
+	rows := goldenRows(t)
+	rows[0].Label = "hot | cold"
+
+	u, err := lookupUnit("eth", "ETH", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	renderMarkdown(&out, rows, u, nil)
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(rows)+2 {
+		t.Fatalf("got %d lines\n%s", len(lines), out.String())
+	}
+	if !regexp.MustCompile(`^\|(-+\|)+$`).MatchString(lines[1]) {
+		t.Errorf("separator line is %q", lines[1])
+	}
+
+	// Unescaped pipes are what split the cells, so every line has the same number of them
+	cells := func(line string) int { return len(regexp.MustCompile(`(^|[^\\])\|`).FindAllString(line, -1)) }
+	for i, line := range lines {
+		if cells(line) != cells(lines[0]) {
+			t.Errorf("line %d has %d cells, the header %d: %q", i+1, cells(line), cells(lines[0]), line)
+		}
+	}
+	if !strings.Contains(out.String(), `hot \| cold`) {
+		t.Errorf("pipe in the label is not escaped\n%s", out.String())
+	}
+}
+
+func TestNoChangesNamesTheRange(t *testing.T) {
+	for _, tc := range []struct {
+		from, to int64
+		want     string
+	}{
+		{3, 5, "No balance changes found in blocks 3–5"},
+		{4, 4, "No balance changes found in block 4"},
+		{5, 4, "No balance changes found, no blocks were scanned"},
+	} {
+		if got := noChanges(&parser.Result{From: big.NewInt(tc.from), To: big.NewInt(tc.to)}); got != tc.want {
+			t.Errorf("%d to %d: %q, want %q", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+// A range of empty blocks says so in words, machine readable formats stay valid and empty
+func TestEmptyBlocksInEveryFormat(t *testing.T) {
+	for format, want := range map[string]string{
+		"table":    "No balance changes found in blocks 3–5\n",
+		"markdown": "No balance changes found in blocks 3–5\n",
+		"json":     "[]\n",
+		"ndjson":   "",
+		"csv":      "rank,address,change_eth,change_wei,tx_count\n",
+	} {
+		t.Run(format, func(t *testing.T) {
+			node, server := newFakeNode(t, 100)
+			node.empty = true
+
+			opts := testOptions(t, server.URL)
+			opts.from, opts.to = 3, 5
+			opts.format = format
+
+			if err := runParser(context.Background(), opts, parser.Config{Workers: 2}); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(opts.out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != want {
+				t.Errorf("got %q, want %q", data, want)
+			}
+		})
+	}
+}
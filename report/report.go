@@ -0,0 +1,122 @@
+// Package report holds the output formats of a scan report, keyed by their -format name.
+//
+// The built in formats register themselves from the getblocktz command, programs using the
+// parser package can register their own renderers the same way.
+package report
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Row is one ranked address of a report, amounts are in wei
+type Row struct {
+	Address  string
+	Change   *big.Int
+	Sent     *big.Int
+	Received *big.Int
+	TxCount  int
+
+	// Filled in by the lookups that were asked for, empty otherwise
+	Label string
+	Name  string
+	Kind  string
+}
+
+// Unit is how amounts are shown to people
+type Unit struct {
+	Label  string
+	Format func(wei *big.Int) string
+}
+
+// Info is everything about a report a renderer may want besides the rows
+type Info struct {
+	Unit     Unit
+	Currency string
+	Decimals int
+	Result   *parser.Result
+	Took     time.Duration
+
+	// Include what changes from run to run of the same scan, like how long it took
+	Metadata bool
+
+	// The table columns picked by name, nil for the default set
+	Columns []string
+}
+
+// Renderer writes the ranked rows of a report in one output format
+// The rows are final, already filtered, limited, sorted and annotated
+type Renderer interface {
+	Render(w io.Writer, rows []Row, info Info) error
+}
+
+// RendererFunc lets a plain function serve as a Renderer
+type RendererFunc func(w io.Writer, rows []Row, info Info) error
+
+func (f RendererFunc) Render(w io.Writer, rows []Row, info Info) error {
+	return f(w, rows, info)
+}
+
+// Format is one registered -format value and how its output is served
+type Format struct {
+	Renderer    Renderer
+	ContentType string
+
+	// Machine readable output stays a valid, empty document when there is nothing to show
+	// Every other format says so in words
+	Machine bool
+}
+
+var (
+	mu      sync.RWMutex
+	formats = map[string]Format{}
+
+	// In the order they were registered, which is the order the usage lists them in
+	names []string
+)
+
+// Register makes a renderer available as -format name, registering a name twice replaces the earlier renderer
+func Register(name, contentType string, machine bool, renderer Renderer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := formats[name]; !ok {
+		names = append(names, name)
+	}
+
+	formats[name] = Format{Renderer: renderer, ContentType: contentType, Machine: machine}
+}
+
+// Lookup returns the format registered as name
+func Lookup(name string) (Format, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	format, ok := formats[name]
+	return format, ok
+}
+
+// Names lists the registered formats in the order they were registered
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return append([]string(nil), names...)
+}
+
+// List is the registered formats for messages, like "table, json or csv"
+func List() string {
+	names := Names()
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+
+	last := len(names) - 1
+	return fmt.Sprintf("%s or %s", strings.Join(names[:last], ", "), names[last])
+}
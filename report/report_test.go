@@ -0,0 +1,81 @@
+package report
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+)
+
+// The tests that register formats do it in a registry of their own, so they can run again in the same process
+func TestRegisterAddsAFormat(t *testing.T) {
+	defer func(saved map[string]Format, order []string) { formats, names = saved, order }(formats, names)
+	formats, names = map[string]Format{}, nil
+
+	var got []Row
+	Register("test-rows", "text/x-rows", true, RendererFunc(func(w io.Writer, rows []Row, info Info) error {
+		got = rows
+		_, err := io.WriteString(w, info.Unit.Label)
+		return err
+	}))
+
+	format, ok := Lookup("test-rows")
+	if !ok {
+		t.Fatal("test-rows is not registered")
+	}
+	if format.ContentType != "text/x-rows" || !format.Machine {
+		t.Errorf("format %+v", format)
+	}
+
+	var out bytes.Buffer
+	rows := []Row{{Address: "0xa", Change: big.NewInt(1)}}
+	if err := format.Renderer.Render(&out, rows, Info{Unit: Unit{Label: "wei"}}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "wei" || len(got) != 1 || got[0].Address != "0xa" {
+		t.Errorf("rendered %q with rows %v", out.String(), got)
+	}
+}
+
+func TestRegisterTwiceReplaces(t *testing.T) {
+	defer func(saved map[string]Format, order []string) { formats, names = saved, order }(formats, names)
+	formats, names = map[string]Format{}, nil
+
+	first := RendererFunc(func(io.Writer, []Row, Info) error { return nil })
+	Register("test-twice", "text/plain", false, first)
+	Register("test-twice", "text/csv", false, first)
+
+	if len(Names()) != 1 {
+		t.Errorf("names %v, want test-twice once", Names())
+	}
+	if format, _ := Lookup("test-twice"); format.ContentType != "text/csv" {
+		t.Errorf("content type %q, want the second registration", format.ContentType)
+	}
+}
+
+func TestListKeepsRegistrationOrder(t *testing.T) {
+	defer func(saved map[string]Format, order []string) { formats, names = saved, order }(formats, names)
+	formats, names = map[string]Format{}, nil
+
+	if List() != "" {
+		t.Errorf("empty list %q", List())
+	}
+
+	none := RendererFunc(func(io.Writer, []Row, Info) error { return nil })
+	Register("table", "", false, none)
+	if List() != "table" {
+		t.Errorf("one format %q", List())
+	}
+
+	Register("json", "", true, none)
+	Register("csv", "", true, none)
+	if List() != "table, json or csv" {
+		t.Errorf("three formats %q", List())
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	if _, ok := Lookup("no-such-format"); ok {
+		t.Error("found a format that was never registered")
+	}
+}
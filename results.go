@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ofen/getblock-go/eth"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// Sort addresses by total balance change, biggest gain first
+// Map order is random, so equal changes are ordered by address to give the same output on every run
+func sortAddresses(balances map[string]*big.Int) []string {
+	keys := make([]string, 0, len(balances))
+
+	for key := range balances {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if c := balances[keys[i]].Cmp(balances[keys[j]]); c != 0 {
+			return c > 0
+		}
+		return keys[i] < keys[j]
+	})
+
+	return keys
+}
+
+// Keep only the top movers by absolute change, or the bottom N biggest losers
+// The input must already be sorted by net change and the output keeps that order
+func limitAddresses(keys []string, balances map[string]*big.Int, top int, bottom int) []string {
+	if bottom > 0 {
+		if bottom > len(keys) {
+			bottom = len(keys)
+		}
+
+		return keys[len(keys)-bottom:]
+	}
+
+	if top <= 0 || top >= len(keys) {
+		return keys
+	}
+
+	// Rank by size of the move regardless of direction
+	byAbs := append([]string(nil), keys...)
+	sort.SliceStable(byAbs, func(i, j int) bool {
+		return new(big.Int).Abs(balances[byAbs[i]]).Cmp(new(big.Int).Abs(balances[byAbs[j]])) > 0
+	})
+
+	keep := make(map[string]bool, top)
+	for _, key := range byAbs[:top] {
+		keep[key] = true
+	}
+
+	limited := make([]string, 0, top)
+	for _, key := range keys {
+		if keep[key] {
+			limited = append(limited, key)
+		}
+	}
+
+	return limited
+}
+
+// Orderings selectable with -sort, each one compares two rows ascending
+// Checksumming mixes the case of addresses, so they compare lowercase to keep a plain hex order
+var sortKeys = map[string]func(a, b report.Row) int{
+	"net":      func(a, b report.Row) int { return a.Change.Cmp(b.Change) },
+	"abs":      func(a, b report.Row) int { return new(big.Int).Abs(a.Change).Cmp(new(big.Int).Abs(b.Change)) },
+	"sent":     func(a, b report.Row) int { return a.Sent.Cmp(b.Sent) },
+	"received": func(a, b report.Row) int { return a.Received.Cmp(b.Received) },
+	"txcount":  func(a, b report.Row) int { return a.TxCount - b.TxCount },
+	"address": func(a, b report.Row) int {
+		return strings.Compare(strings.ToLower(a.Address), strings.ToLower(b.Address))
+	},
+}
+
+// Reorder the rows for display, ties are broken by address so the order never depends on how the rows came in
+// The address tie break is ascending whatever the direction, so flipping -order doesn't reshuffle equal rows
+func sortRows(rows []report.Row, key string, descending bool) {
+	compare := sortKeys[key]
+	byAddress := sortKeys["address"]
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		c := compare(rows[i], rows[j])
+		if descending {
+			c = -c
+		}
+		if c == 0 {
+			return byAddress(rows[i], rows[j]) < 0
+		}
+		return c < 0
+	})
+}
+
+// Drop addresses whose absolute net change is below minWei
+// This looks at the net total per address, not at individual transactions
+func filterDust(keys []string, balances map[string]*big.Int, minWei *big.Int) []string {
+	if minWei == nil || minWei.Sign() <= 0 {
+		return keys
+	}
+
+	kept := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if new(big.Int).Abs(balances[key]).Cmp(minWei) >= 0 {
+			kept = append(kept, key)
+		}
+	}
+
+	return kept
+}
+
+// Convert a decimal ETH amount like "0.05" into wei without going through a float
+// Anything finer than one wei is rounded up so the threshold never lets a smaller value through
+func parseEther(amount string) (*big.Int, error) {
+	ether, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return nil, fmt.Errorf("invalid ETH amount %q", amount)
+	}
+
+	if ether.Sign() < 0 {
+		return nil, fmt.Errorf("ETH amount must not be negative, got %q", amount)
+	}
+
+	wei := new(big.Rat).Mul(ether, new(big.Rat).SetInt(big.NewInt(eth.Ether)))
+
+	quotient, remainder := new(big.Int).QuoRem(wei.Num(), wei.Denom(), new(big.Int))
+	if remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+
+	return quotient, nil
+}
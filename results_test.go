@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// Addresses 0x..01 to 0x..n, address i changed by i wei, every other one as a loss
+func mixedBalances(n int) map[string]*big.Int {
+	balances := map[string]*big.Int{}
+	for i := 1; i <= n; i++ {
+		change := big.NewInt(int64(i))
+		if i%2 == 0 {
+			change.Neg(change)
+		}
+		balances[fmt.Sprintf("0x%040x", i)] = change
+	}
+	return balances
+}
+
+// Render the balances as JSON the way a scan would and decode the rows
+func renderedRows(t *testing.T, opts options, balances map[string]*big.Int) []jsonResult {
+	t.Helper()
+
+	if opts.format == "" {
+		opts.format, opts.sortKey, opts.descending = "json", "net", true
+	}
+
+	var out strings.Builder
+	if err := writeResults(context.Background(), &out, opts, nil, &parser.Result{Balances: balances}, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []jsonResult
+	if err := json.Unmarshal([]byte(out.String()), &rows); err != nil {
+		t.Fatalf("%v in %s", err, out.String())
+	}
+	return rows
+}
+
+func TestTopKeepsTheBiggestMoves(t *testing.T) {
+	rows := renderedRows(t, options{top: 5}, mixedBalances(20))
+
+	// 20, 19, 18, 17 and 16 moved the most, gains and losses alike, shown biggest gain first
+	var got []string
+	for _, row := range rows {
+		got = append(got, row.ChangeWei)
+	}
+	if want := "19 17 -16 -18 -20"; strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
+
+func TestBottomKeepsTheBiggestLosers(t *testing.T) {
+	rows := renderedRows(t, options{bottom: 2}, mixedBalances(20))
+
+	if len(rows) != 2 || rows[0].ChangeWei != "-18" || rows[1].ChangeWei != "-20" {
+		t.Errorf("got %v", rows)
+	}
+}
+
+func TestTopLargerThanTheResultKeepsEverything(t *testing.T) {
+	if rows := renderedRows(t, options{top: 50}, mixedBalances(20)); len(rows) != 20 {
+		t.Errorf("got %d rows, want all 20", len(rows))
+	}
+}
+
+func TestMinEthDropsDust(t *testing.T) {
+	minWei, err := parseEther("0.000000000000000010")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only 10 and up in either direction are kept, the net total counts rather than single transfers
+	rows := renderedRows(t, options{minWei: minWei}, mixedBalances(12))
+
+	var got []string
+	for _, row := range rows {
+		got = append(got, row.ChangeWei)
+	}
+	if want := "11 -10 -12"; strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
+
+func TestParseEther(t *testing.T) {
+	for amount, want := range map[string]string{
+		"1":                      "1000000000000000000",
+		"0.05":                   "50000000000000000",
+		"0":                      "0",
+		"0.0000000000000000001":  "1",
+		"1.0000000000000000001":  "1000000000000000001",
+		"123456789.123456789123": "123456789123456789123000000",
+	} {
+		got, err := parseEther(amount)
+		if err != nil || got.String() != want {
+			t.Errorf("parseEther(%s) = %v, %v, want %s", amount, got, err, want)
+		}
+	}
+
+	for _, bad := range []string{"-1", "lots", ""} {
+		if _, err := parseEther(bad); err == nil {
+			t.Errorf("parseEther(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	// Big enough that comparing anything but the big.Int would get it wrong
+	huge := new(big.Int).Lsh(big.NewInt(1), 70)
+
+	const (
+		a = "0x000000000000000000000000000000000000000a"
+		b = "0x000000000000000000000000000000000000000B"
+		c = "0x000000000000000000000000000000000000000c"
+	)
+	rows := func() []report.Row {
+		return []report.Row{
+			{Address: c, Change: big.NewInt(10), Sent: big.NewInt(0), Received: big.NewInt(10), TxCount: 2},
+			{Address: a, Change: big.NewInt(5), Sent: big.NewInt(10), Received: big.NewInt(15), TxCount: 3},
+			{Address: b, Change: new(big.Int).Neg(huge), Sent: huge, Received: big.NewInt(0), TxCount: 1},
+		}
+	}
+
+	for _, tc := range []struct {
+		key        string
+		descending bool
+		want       []string
+	}{
+		{"net", true, []string{c, a, b}},
+		{"net", false, []string{b, a, c}},
+		{"abs", true, []string{b, c, a}},
+		{"abs", false, []string{a, c, b}},
+		{"sent", true, []string{b, a, c}},
+		{"received", true, []string{a, c, b}},
+		{"txcount", true, []string{a, c, b}},
+		{"txcount", false, []string{b, c, a}},
+		// Lowercase, or the checksummed 0B would sort before 0a
+		{"address", false, []string{a, b, c}},
+		{"address", true, []string{c, b, a}},
+	} {
+		sorted := rows()
+		sortRows(sorted, tc.key, tc.descending)
+
+		var got []string
+		for _, r := range sorted {
+			got = append(got, r.Address)
+		}
+		if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Errorf("-sort %s descending %v gave %v, want %v", tc.key, tc.descending, got, tc.want)
+		}
+	}
+}
+
+// Equal totals come out in address order, whichever way the map hands them over and whatever the format
+func TestTiesAreOrderedByAddress(t *testing.T) {
+	balances := map[string]*big.Int{}
+	for i := 1; i <= 20; i++ {
+		balances[fmt.Sprintf("0x%040x", i)] = big.NewInt(7)
+	}
+	balances[fmt.Sprintf("0x%040x", 100)] = big.NewInt(8)
+
+	for _, format := range []string{"table", "json", "csv"} {
+		opts := options{format: format, sortKey: "net", descending: true, noMetadata: true, unit: report.Unit{Label: "wei", Format: func(wei *big.Int) string { return wei.String() }}}
+
+		var first string
+		for run := 0; run < 20; run++ {
+			var out strings.Builder
+			if err := writeResults(context.Background(), &out, opts, nil, &parser.Result{Balances: balances}, time.Second); err != nil {
+				t.Fatal(err)
+			}
+			if run == 0 {
+				first = out.String()
+			} else if out.String() != first {
+				t.Fatalf("%s output changed between runs\n%s\n%s", format, first, out.String())
+			}
+		}
+	}
+
+	rows := renderedRows(t, options{}, balances)
+	if rows[0].Address != fmt.Sprintf("0x%040x", 100) {
+		t.Errorf("largest change isn't first: %s", rows[0].Address)
+	}
+	for i := 2; i < len(rows); i++ {
+		if rows[i-1].Address > rows[i].Address {
+			t.Errorf("tie at %d out of order: %s before %s", i, rows[i-1].Address, rows[i].Address)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v4.25.0
+// source: blockparser.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ScanRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	From    uint64 `protobuf:"varint,1,opt,name=from,proto3" json:"from,omitempty"`
+	To      uint64 `protobuf:"varint,2,opt,name=to,proto3" json:"to,omitempty"`
+	Workers int32  `protobuf:"varint,3,opt,name=workers,proto3" json:"workers,omitempty"`
+}
+
+func (x *ScanRequest) Reset() {
+	*x = ScanRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockparser_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ScanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanRequest) ProtoMessage() {}
+
+func (x *ScanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_blockparser_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanRequest.ProtoReflect.Descriptor instead.
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return file_blockparser_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ScanRequest) GetFrom() uint64 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *ScanRequest) GetTo() uint64 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+func (x *ScanRequest) GetWorkers() int32 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+type ScanUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Update:
+	//	*ScanUpdate_Block
+	//	*ScanUpdate_Total
+	Update isScanUpdate_Update `protobuf_oneof:"update"`
+}
+
+func (x *ScanUpdate) Reset() {
+	*x = ScanUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockparser_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ScanUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanUpdate) ProtoMessage() {}
+
+func (x *ScanUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_blockparser_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanUpdate.ProtoReflect.Descriptor instead.
+func (*ScanUpdate) Descriptor() ([]byte, []int) {
+	return file_blockparser_proto_rawDescGZIP(), []int{1}
+}
+
+func (m *ScanUpdate) GetUpdate() isScanUpdate_Update {
+	if m != nil {
+		return m.Update
+	}
+	return nil
+}
+
+func (x *ScanUpdate) GetBlock() *BlockChanges {
+	if x, ok := x.GetUpdate().(*ScanUpdate_Block); ok {
+		return x.Block
+	}
+	return nil
+}
+
+func (x *ScanUpdate) GetTotal() *BalanceChange {
+	if x, ok := x.GetUpdate().(*ScanUpdate_Total); ok {
+		return x.Total
+	}
+	return nil
+}
+
+type isScanUpdate_Update interface {
+	isScanUpdate_Update()
+}
+
+type ScanUpdate_Block struct {
+	Block *BlockChanges `protobuf:"bytes,1,opt,name=block,proto3,oneof"`
+}
+
+type ScanUpdate_Total struct {
+	Total *BalanceChange `protobuf:"bytes,2,opt,name=total,proto3,oneof"`
+}
+
+func (*ScanUpdate_Block) isScanUpdate_Update() {}
+
+func (*ScanUpdate_Total) isScanUpdate_Update() {}
+
+type BlockChanges struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Number  uint64           `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	Changes []*BalanceChange `protobuf:"bytes,2,rep,name=changes,proto3" json:"changes,omitempty"`
+}
+
+func (x *BlockChanges) Reset() {
+	*x = BlockChanges{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockparser_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockChanges) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockChanges) ProtoMessage() {}
+
+func (x *BlockChanges) ProtoReflect() protoreflect.Message {
+	mi := &file_blockparser_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockChanges.ProtoReflect.Descriptor instead.
+func (*BlockChanges) Descriptor() ([]byte, []int) {
+	return file_blockparser_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BlockChanges) GetNumber() uint64 {
+	if x != nil {
+		return x.Number
+	}
+	return 0
+}
+
+func (x *BlockChanges) GetChanges() []*BalanceChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+type BalanceChange struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Address      string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	ChangeWei    string `protobuf:"bytes,2,opt,name=change_wei,json=changeWei,proto3" json:"change_wei,omitempty"`
+	SentWei      string `protobuf:"bytes,3,opt,name=sent_wei,json=sentWei,proto3" json:"sent_wei,omitempty"`
+	ReceivedWei  string `protobuf:"bytes,4,opt,name=received_wei,json=receivedWei,proto3" json:"received_wei,omitempty"`
+	Transactions int64  `protobuf:"varint,5,opt,name=transactions,proto3" json:"transactions,omitempty"`
+}
+
+func (x *BalanceChange) Reset() {
+	*x = BalanceChange{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_blockparser_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BalanceChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BalanceChange) ProtoMessage() {}
+
+func (x *BalanceChange) ProtoReflect() protoreflect.Message {
+	mi := &file_blockparser_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BalanceChange.ProtoReflect.Descriptor instead.
+func (*BalanceChange) Descriptor() ([]byte, []int) {
+	return file_blockparser_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BalanceChange) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *BalanceChange) GetChangeWei() string {
+	if x != nil {
+		return x.ChangeWei
+	}
+	return ""
+}
+
+func (x *BalanceChange) GetSentWei() string {
+	if x != nil {
+		return x.SentWei
+	}
+	return ""
+}
+
+func (x *BalanceChange) GetReceivedWei() string {
+	if x != nil {
+		return x.ReceivedWei
+	}
+	return ""
+}
+
+func (x *BalanceChange) GetTransactions() int64 {
+	if x != nil {
+		return x.Transactions
+	}
+	return 0
+}
+
+var File_blockparser_proto protoreflect.FileDescriptor
+
+var file_blockparser_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x70, 0x61, 0x72, 0x73, 0x65, 0x72, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x0b, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x70, 0x61, 0x72, 0x73, 0x65, 0x72,
+	0x22, 0x4b, 0x0a, 0x0b, 0x53, 0x63, 0x61, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x66,
+	0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x02, 0x74, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x73, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x77, 0x6f, 0x72, 0x6b, 0x65, 0x72, 0x73, 0x22, 0x7d, 0x0a,
+	0x0a, 0x53, 0x63, 0x61, 0x6e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x31, 0x0a, 0x05, 0x62,
+	0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x62, 0x6c, 0x6f,
+	0x63, 0x6b, 0x70, 0x61, 0x72, 0x73, 0x65, 0x72, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43, 0x68,
+	0x61, 0x6e, 0x67, 0x65, 0x73, 0x48, 0x00, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x12, 0x32,
+	0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x70, 0x61, 0x72, 0x73, 0x65, 0x72, 0x2e, 0x42, 0x61, 0x6c, 0x61,
+	0x6e, 0x63, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x48, 0x00, 0x52, 0x05, 0x74, 0x6f, 0x74,
+	0x61, 0x6c, 0x42, 0x08, 0x0a, 0x06, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x22, 0x5c, 0x0a, 0x0c,
+	0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x12, 0x16, 0x0a, 0x06,
+	0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6e, 0x75,
+	0x6d, 0x62, 0x65, 0x72, 0x12, 0x34, 0x0a, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x70, 0x61, 0x72,
+	0x73, 0x65, 0x72, 0x2e, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x67,
+	0x65, 0x52, 0x07, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x73, 0x22, 0xaa, 0x01, 0x0a, 0x0d, 0x42,
+	0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65,
+	0x5f, 0x77, 0x65, 0x69, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x68, 0x61, 0x6e,
+	0x67, 0x65, 0x57, 0x65, 0x69, 0x12, 0x19, 0x0a, 0x08, 0x73, 0x65, 0x6e, 0x74, 0x5f, 0x77, 0x65,
+	0x69, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x6e, 0x74, 0x57, 0x65, 0x69,
+	0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x5f, 0x77, 0x65, 0x69,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64,
+	0x57, 0x65, 0x69, 0x12, 0x22, 0x0a, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73,
+	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x32, 0x4a, 0x0a, 0x0b, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
+	0x50, 0x61, 0x72, 0x73, 0x65, 0x72, 0x12, 0x3b, 0x0a, 0x04, 0x53, 0x63, 0x61, 0x6e, 0x12, 0x18,
+	0x2e, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x70, 0x61, 0x72, 0x73, 0x65, 0x72, 0x2e, 0x53, 0x63, 0x61,
+	0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
+	0x70, 0x61, 0x72, 0x73, 0x65, 0x72, 0x2e, 0x53, 0x63, 0x61, 0x6e, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x30, 0x01, 0x42, 0x24, 0x5a, 0x22, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x73, 0x61, 0x6d, 0x73, 0x68, 0x65, 0x66, 0x66, 0x2f, 0x67, 0x65, 0x74, 0x62, 0x6c,
+	0x6f, 0x63, 0x6b, 0x74, 0x7a, 0x2f, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var (
+	file_blockparser_proto_rawDescOnce sync.Once
+	file_blockparser_proto_rawDescData = file_blockparser_proto_rawDesc
+)
+
+func file_blockparser_proto_rawDescGZIP() []byte {
+	file_blockparser_proto_rawDescOnce.Do(func() {
+		file_blockparser_proto_rawDescData = protoimpl.X.CompressGZIP(file_blockparser_proto_rawDescData)
+	})
+	return file_blockparser_proto_rawDescData
+}
+
+var file_blockparser_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_blockparser_proto_goTypes = []any{
+	(*ScanRequest)(nil),   // 0: blockparser.ScanRequest
+	(*ScanUpdate)(nil),    // 1: blockparser.ScanUpdate
+	(*BlockChanges)(nil),  // 2: blockparser.BlockChanges
+	(*BalanceChange)(nil), // 3: blockparser.BalanceChange
+}
+var file_blockparser_proto_depIdxs = []int32{
+	2, // 0: blockparser.ScanUpdate.block:type_name -> blockparser.BlockChanges
+	3, // 1: blockparser.ScanUpdate.total:type_name -> blockparser.BalanceChange
+	3, // 2: blockparser.BlockChanges.changes:type_name -> blockparser.BalanceChange
+	0, // 3: blockparser.BlockParser.Scan:input_type -> blockparser.ScanRequest
+	1, // 4: blockparser.BlockParser.Scan:output_type -> blockparser.ScanUpdate
+	4, // [4:5] is the sub-list for method output_type
+	3, // [3:4] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_blockparser_proto_init() }
+func file_blockparser_proto_init() {
+	if File_blockparser_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_blockparser_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*ScanRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_blockparser_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*ScanUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_blockparser_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*BlockChanges); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_blockparser_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*BalanceChange); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_blockparser_proto_msgTypes[1].OneofWrappers = []any{
+		(*ScanUpdate_Block)(nil),
+		(*ScanUpdate_Total)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_blockparser_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_blockparser_proto_goTypes,
+		DependencyIndexes: file_blockparser_proto_depIdxs,
+		MessageInfos:      file_blockparser_proto_msgTypes,
+	}.Build()
+	File_blockparser_proto = out.File
+	file_blockparser_proto_rawDesc = nil
+	file_blockparser_proto_goTypes = nil
+	file_blockparser_proto_depIdxs = nil
+}
@@ -0,0 +1,136 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.0
+// source: blockparser.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	BlockParser_Scan_FullMethodName = "/blockparser.BlockParser/Scan"
+)
+
+// BlockParserClient is the client API for BlockParser service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BlockParserClient interface {
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (BlockParser_ScanClient, error)
+}
+
+type blockParserClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBlockParserClient(cc grpc.ClientConnInterface) BlockParserClient {
+	return &blockParserClient{cc}
+}
+
+func (c *blockParserClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (BlockParser_ScanClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BlockParser_ServiceDesc.Streams[0], BlockParser_Scan_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &blockParserScanClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BlockParser_ScanClient interface {
+	Recv() (*ScanUpdate, error)
+	grpc.ClientStream
+}
+
+type blockParserScanClient struct {
+	grpc.ClientStream
+}
+
+func (x *blockParserScanClient) Recv() (*ScanUpdate, error) {
+	m := new(ScanUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BlockParserServer is the server API for BlockParser service.
+// All implementations must embed UnimplementedBlockParserServer
+// for forward compatibility
+type BlockParserServer interface {
+	Scan(*ScanRequest, BlockParser_ScanServer) error
+	mustEmbedUnimplementedBlockParserServer()
+}
+
+// UnimplementedBlockParserServer must be embedded to have forward compatible implementations.
+type UnimplementedBlockParserServer struct {
+}
+
+func (UnimplementedBlockParserServer) Scan(*ScanRequest, BlockParser_ScanServer) error {
+	return status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedBlockParserServer) mustEmbedUnimplementedBlockParserServer() {}
+
+// UnsafeBlockParserServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BlockParserServer will
+// result in compilation errors.
+type UnsafeBlockParserServer interface {
+	mustEmbedUnimplementedBlockParserServer()
+}
+
+func RegisterBlockParserServer(s grpc.ServiceRegistrar, srv BlockParserServer) {
+	s.RegisterService(&BlockParser_ServiceDesc, srv)
+}
+
+func _BlockParser_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockParserServer).Scan(m, &blockParserScanServer{stream})
+}
+
+type BlockParser_ScanServer interface {
+	Send(*ScanUpdate) error
+	grpc.ServerStream
+}
+
+type blockParserScanServer struct {
+	grpc.ServerStream
+}
+
+func (x *blockParserScanServer) Send(m *ScanUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// BlockParser_ServiceDesc is the grpc.ServiceDesc for BlockParser service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BlockParser_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "blockparser.BlockParser",
+	HandlerType: (*BlockParserServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Scan",
+			Handler:       _BlockParser_Scan_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "blockparser.proto",
+}
@@ -0,0 +1,161 @@
+// Package rpc serves scans over gRPC, the service is defined in blockparser.proto
+//
+// Regenerate the code after changing the proto with
+//
+//	protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative blockparser.proto
+package rpc
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/samsheff/getblocktz/parser"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Limits keep a single caller from tying up the server, the same ones -serve has
+type Limits struct {
+	// Largest range one call may scan, zero for no limit
+	MaxBlocks int64
+
+	// Scans running at once, further calls fail with ResourceExhausted, zero for no limit
+	MaxScans int
+}
+
+// Server implements BlockParserServer on top of parser.Scan
+type Server struct {
+	UnimplementedBlockParserServer
+
+	client    parser.Client
+	config    parser.Config
+	maxBlocks int64
+
+	// One token per running scan, nil when there is no limit
+	slots chan struct{}
+}
+
+// NewServer runs every scan against client with config, requests can only lower the number of workers
+// Settings that belong to a single run like checkpoints and progress are dropped
+func NewServer(client parser.Client, config parser.Config, limits Limits) *Server {
+	config.Checkpoint = ""
+	config.Progress = nil
+	config.OnBlock = nil
+	config.Seen = nil
+
+	s := &Server{client: client, config: config, maxBlocks: limits.MaxBlocks}
+	if limits.MaxScans > 0 {
+		s.slots = make(chan struct{}, limits.MaxScans)
+	}
+
+	return s
+}
+
+// Scan runs the requested range and streams the changes of every block as soon as it is counted
+// The totals per address follow once every block is in, biggest gain first
+// A client that cancels the call stops the scan
+func (s *Server) Scan(request *ScanRequest, stream BlockParser_ScanServer) error {
+	if request.To < request.From {
+		return status.Errorf(codes.InvalidArgument, "invalid block range %d to %d", request.From, request.To)
+	}
+	if s.maxBlocks > 0 && request.To-request.From >= uint64(s.maxBlocks) {
+		return status.Errorf(codes.InvalidArgument, "at most %d blocks per scan", s.maxBlocks)
+	}
+
+	config := s.config
+	if request.Workers < 0 {
+		return status.Error(codes.InvalidArgument, "workers must not be negative")
+	}
+	if request.Workers > 0 && int(request.Workers) < config.Workers {
+		config.Workers = int(request.Workers)
+	}
+
+	if s.slots != nil {
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+		default:
+			return status.Error(codes.ResourceExhausted, "too many scans running, try again later")
+		}
+	}
+
+	// A client that stops reading ends the scan, there is no one left to send the rest to
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	// OnBlock runs in the aggregation loop inside Scan, which is this goroutine, so sending from it is safe
+	var sendErr error
+	config.OnBlock = func(block *big.Int, changes map[string]*big.Int) {
+		if sendErr != nil {
+			return
+		}
+		if sendErr = stream.Send(blockUpdate(block, changes)); sendErr != nil {
+			cancel()
+		}
+	}
+
+	from, to := new(big.Int).SetUint64(request.From), new(big.Int).SetUint64(request.To)
+
+	result, err := parser.Scan(ctx, s.client, from, to, config)
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		if stream.Context().Err() != nil {
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	// A short scan is still a result, but the client has to be told which blocks are missing
+	if len(result.Failed) > 0 {
+		return s.send(stream, result, status.Errorf(codes.Unavailable, "%d blocks could not be fetched, first %d", len(result.Failed), result.Failed[0]))
+	}
+
+	return s.send(stream, result, nil)
+}
+
+// The changes of one block, sorted so identical scans stream identically
+func blockUpdate(block *big.Int, changes map[string]*big.Int) *ScanUpdate {
+	addresses := make([]string, 0, len(changes))
+	for address := range changes {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	update := &BlockChanges{Number: block.Uint64(), Changes: make([]*BalanceChange, 0, len(addresses))}
+	for _, address := range addresses {
+		update.Changes = append(update.Changes, &BalanceChange{Address: address, ChangeWei: changes[address].String()})
+	}
+
+	return &ScanUpdate{Update: &ScanUpdate_Block{Block: update}}
+}
+
+func (s *Server) send(stream BlockParser_ScanServer, result *parser.Result, final error) error {
+	addresses := make([]string, 0, len(result.Balances))
+	for address := range result.Balances {
+		addresses = append(addresses, address)
+	}
+
+	// Same order as the CLI, ties broken by address so identical scans stream identically
+	sort.Slice(addresses, func(i, j int) bool {
+		if c := result.Balances[addresses[i]].Cmp(result.Balances[addresses[j]]); c != 0 {
+			return c > 0
+		}
+		return addresses[i] < addresses[j]
+	})
+
+	for _, address := range addresses {
+		change := &BalanceChange{Address: address, ChangeWei: result.Balances[address].String(), SentWei: "0", ReceivedWei: "0"}
+		if flow, ok := result.Flows[address]; ok {
+			change.SentWei, change.ReceivedWei, change.Transactions = flow.Sent.String(), flow.Received.String(), int64(flow.Transactions)
+		}
+
+		if err := stream.Send(&ScanUpdate{Update: &ScanUpdate_Total{Total: change}}); err != nil {
+			return err
+		}
+	}
+
+	return final
+}
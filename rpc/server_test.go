@@ -0,0 +1,237 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/ybbus/jsonrpc/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// A chain where block n has one transfer of n wei from 0xa to 0xb
+// Blocks in fail answer with an error, and every call waits for release when it is set
+// Calls are announced on called when it is set
+type fakeChain struct {
+	fail    map[uint64]bool
+	release chan struct{}
+	called  chan struct{}
+}
+
+func (c *fakeChain) Call(ctx context.Context, method string, params ...interface{}) (*jsonrpc.RPCResponse, error) {
+	if c.called != nil {
+		select {
+		case c.called <- struct{}{}:
+		default:
+		}
+	}
+	if c.release != nil {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if method != "eth_getBlockByNumber" {
+		return nil, fmt.Errorf("unexpected %s call", method)
+	}
+
+	n, err := strconv.ParseUint(params[0].(string), 0, 64)
+	if err != nil {
+		return nil, err
+	}
+	if c.fail[n] {
+		return nil, errors.New("node unavailable")
+	}
+
+	return &jsonrpc.RPCResponse{Result: map[string]interface{}{
+		"number":     fmt.Sprintf("%#x", n),
+		"hash":       fmt.Sprintf("0x%064x", n),
+		"parentHash": fmt.Sprintf("0x%064x", n-1),
+		"transactions": []interface{}{map[string]interface{}{
+			"hash":  fmt.Sprintf("0x%063x1", n),
+			"from":  "0x000000000000000000000000000000000000000a",
+			"to":    "0x000000000000000000000000000000000000000b",
+			"value": fmt.Sprintf("%#x", n),
+		}},
+	}}, nil
+}
+
+// Start the service on an in-memory listener and return a client for it
+func dial(t *testing.T, chain parser.Client, limits Limits) BlockParserClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	RegisterBlockParserServer(server, NewServer(chain, parser.Config{Workers: 2}, limits))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return NewBlockParserClient(conn)
+}
+
+// Read the whole stream, the error is the status the call ended with
+func receive(t *testing.T, stream BlockParser_ScanClient) ([]*BlockChanges, []*BalanceChange, error) {
+	t.Helper()
+
+	var blocks []*BlockChanges
+	var totals []*BalanceChange
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			return blocks, totals, nil
+		}
+		if err != nil {
+			return blocks, totals, err
+		}
+
+		switch update := update.Update.(type) {
+		case *ScanUpdate_Block:
+			if len(totals) > 0 {
+				t.Fatalf("block %d arrived after the totals", update.Block.Number)
+			}
+			blocks = append(blocks, update.Block)
+		case *ScanUpdate_Total:
+			totals = append(totals, update.Total)
+		}
+	}
+}
+
+func TestScanStreamsBlocksThenTotals(t *testing.T) {
+	client := dial(t, &fakeChain{}, Limits{})
+
+	stream, err := client.Scan(context.Background(), &ScanRequest{From: 1, To: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, totals, err := receive(t, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[uint64]bool{}
+	for _, block := range blocks {
+		seen[block.Number] = true
+		want := new(big.Int).SetUint64(block.Number)
+		if len(block.Changes) != 2 || block.Changes[0].ChangeWei != new(big.Int).Neg(want).String() || block.Changes[1].ChangeWei != want.String() {
+			t.Errorf("block %d changes %v", block.Number, block.Changes)
+		}
+	}
+	if len(seen) != 4 {
+		t.Errorf("got updates for blocks %v, want 1 to 4", seen)
+	}
+
+	// 1+2+3+4 wei went from a to b, the gain comes first
+	if len(totals) != 2 || totals[0].ChangeWei != "10" || totals[1].ChangeWei != "-10" || totals[0].Transactions != 4 {
+		t.Fatalf("totals %v", totals)
+	}
+	if totals[1].SentWei != "10" || totals[0].ReceivedWei != "10" {
+		t.Errorf("flows %v", totals)
+	}
+}
+
+func TestScanRejectsBadRequests(t *testing.T) {
+	client := dial(t, &fakeChain{}, Limits{MaxBlocks: 10})
+
+	for name, request := range map[string]*ScanRequest{
+		"backwards":   {From: 5, To: 4},
+		"too large":   {From: 1, To: 11},
+		"bad workers": {From: 1, To: 2, Workers: -1},
+	} {
+		stream, err := client.Scan(context.Background(), request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := receive(t, stream); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: got %v, want InvalidArgument", name, err)
+		}
+	}
+
+	// The largest allowed range still runs
+	stream, err := client.Scan(context.Background(), &ScanRequest{From: 1, To: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := receive(t, stream); err != nil {
+		t.Errorf("10 blocks: %v", err)
+	}
+}
+
+func TestScanReportsFailedBlocksAsUnavailable(t *testing.T) {
+	client := dial(t, &fakeChain{fail: map[uint64]bool{2: true}}, Limits{})
+
+	stream, err := client.Scan(context.Background(), &ScanRequest{From: 1, To: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The blocks that made it are still streamed before the error
+	blocks, totals, err := receive(t, stream)
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("got %v, want Unavailable", err)
+	}
+	if len(blocks) != 2 || len(totals) != 2 || totals[0].ChangeWei != "4" {
+		t.Fatalf("blocks %v, totals %v", blocks, totals)
+	}
+}
+
+func TestScanLimitsConcurrentScans(t *testing.T) {
+	chain := &fakeChain{release: make(chan struct{}), called: make(chan struct{}, 1)}
+	client := dial(t, chain, Limits{MaxScans: 1})
+
+	// The first scan holds the only slot until the chain answers
+	first, err := client.Scan(context.Background(), &ScanRequest{From: 1, To: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-chain.called
+
+	second, err := client.Scan(context.Background(), &ScanRequest{From: 1, To: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := receive(t, second); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second scan got %v, want ResourceExhausted", err)
+	}
+
+	close(chain.release)
+	if _, _, err := receive(t, first); err != nil {
+		t.Fatalf("first scan: %v", err)
+	}
+}
+
+func TestScanStopsWhenClientCancels(t *testing.T) {
+	chain := &fakeChain{release: make(chan struct{}), called: make(chan struct{}, 1)}
+	client := dial(t, chain, Limits{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Scan(ctx, &ScanRequest{From: 1, To: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-chain.called
+	cancel()
+
+	if _, _, err := receive(t, stream); status.Code(err) != codes.Canceled {
+		t.Fatalf("got %v, want Canceled", err)
+	}
+}
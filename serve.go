@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+	"github.com/samsheff/getblocktz/report"
+)
+
+// Body of POST /scan, from and to are an inclusive range like -from and -to
+type scanRequest struct {
+	From    *big.Int `json:"from"`
+	To      *big.Int `json:"to"`
+	Workers int      `json:"workers"`
+	Format  string   `json:"format"`
+}
+
+// Runs scans for HTTP clients with the settings the server was started with
+// slots holds one token per running scan, when it is full new requests are turned away
+type scanServer struct {
+	client    parser.Client
+	opts      options
+	config    parser.Config
+	slots     chan struct{}
+	maxBlocks int64
+}
+
+func newScanServer(client parser.Client, opts options, config parser.Config) *scanServer {
+	// Those belong to a single CLI run, a request only ever sees its own range
+	config.Checkpoint = ""
+	config.Progress = nil
+	config.Seen = nil
+
+	return &scanServer{
+		client:    client,
+		opts:      opts,
+		config:    config,
+		slots:     make(chan struct{}, opts.maxScans),
+		maxBlocks: int64(opts.maxBlocks),
+	}
+}
+
+func (s *scanServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scan", s.scan)
+	return mux
+}
+
+func (s *scanServer) scan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request scanRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	opts, config, err := s.settings(request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	default:
+		http.Error(w, "too many scans running, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	// The request context ends the scan when the client goes away
+	start := time.Now()
+	result, err := parser.Scan(r.Context(), s.client, request.From, request.To, config)
+	if err != nil {
+		slog.Warn("scan failed", "from", request.From, "to", request.To, "err", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	// Rendered in full first, so a rendering error can still become a proper status
+	var body bytes.Buffer
+	if err := writeResults(r.Context(), &body, opts, nil, result, time.Since(start)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	format, _ := report.Lookup(opts.format)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("X-Failed-Blocks", fmt.Sprint(len(result.Failed)))
+	w.Write(body.Bytes())
+}
+
+// Check a request and work out the options and config to run it with
+// Workers can only be lowered from what the server was started with
+func (s *scanServer) settings(request scanRequest) (options, parser.Config, error) {
+	opts, config := s.opts, s.config
+
+	if request.From == nil || request.To == nil {
+		return opts, config, errors.New("from and to are required")
+	}
+	if request.From.Sign() < 0 || request.To.Cmp(request.From) < 0 {
+		return opts, config, fmt.Errorf("invalid block range %d to %d", request.From, request.To)
+	}
+
+	span := new(big.Int).Sub(request.To, request.From)
+	if s.maxBlocks > 0 && span.Cmp(big.NewInt(s.maxBlocks-1)) > 0 {
+		return opts, config, fmt.Errorf("at most %d blocks per scan", s.maxBlocks)
+	}
+
+	if request.Workers < 0 {
+		return opts, config, errors.New("workers must not be negative")
+	}
+	if request.Workers > 0 && request.Workers < config.Workers {
+		config.Workers = request.Workers
+	}
+
+	opts.format = "json"
+	if request.Format != "" {
+		opts.format = request.Format
+	}
+	if _, ok := report.Lookup(opts.format); !ok {
+		return opts, config, fmt.Errorf("unknown format %q", request.Format)
+	}
+	if opts.tokens && opts.format != "table" && opts.format != "json" && opts.format != "ndjson" && opts.format != "csv" {
+		return opts, config, fmt.Errorf("token reports only support table, json, ndjson or csv, not %q", opts.format)
+	}
+
+	return opts, config, nil
+}
+
+// Serve POST /scan on opts.serve until ctx is cancelled
+func serve(ctx context.Context, opts options, client parser.Client, config parser.Config) error {
+	return listenAndServe(ctx, opts.serve, newScanServer(client, opts, config).Handler(), "serving scans")
+}
+
+// Serve handler on addr until ctx is cancelled, running requests get a few seconds to finish
+func listenAndServe(ctx context.Context, addr string, handler http.Handler, message string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		server.Shutdown(shutdown)
+	}()
+
+	slog.Info(message, "addr", listener.Addr().String())
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
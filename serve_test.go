@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// A scan server in front of a fake node, one scan at a time and at most 10 blocks each
+func newTestScanServer(t *testing.T) (*scanServer, *httptest.Server) {
+	t.Helper()
+
+	_, node := newFakeNode(t, 100)
+
+	opts := testOptions(t, node.URL)
+	opts.maxScans, opts.maxBlocks = 1, 10
+
+	s := newScanServer(newRPCClient(node.URL, "", nil), opts, parser.Config{Workers: 4})
+	server := httptest.NewServer(s.Handler())
+	t.Cleanup(server.Close)
+
+	return s, server
+}
+
+func postScan(t *testing.T, url, body string) *http.Response {
+	t.Helper()
+
+	resp, err := http.Post(url+"/scan", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestServeScansARange(t *testing.T) {
+	_, server := newTestScanServer(t)
+
+	resp := postScan(t, server.URL, `{"from": 1, "to": 3}`)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" || resp.Header.Get("X-Failed-Blocks") != "0" {
+		t.Fatalf("status %d, headers %v", resp.StatusCode, resp.Header)
+	}
+
+	var rows []jsonResult
+	if err := json.NewDecoder(resp.Body).Decode(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if got := received(t, rows); got.Int64() != 6 {
+		t.Errorf("bob received %s, want 6", got)
+	}
+}
+
+func TestServeRendersTheRequestedFormat(t *testing.T) {
+	_, server := newTestScanServer(t)
+
+	resp := postScan(t, server.URL, `{"from": 1, "to": 3, "format": "csv", "workers": 2}`)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Errorf("records %v, want a header and two rows", records)
+	}
+}
+
+func TestServeRejectsBadRequests(t *testing.T) {
+	_, server := newTestScanServer(t)
+
+	for name, body := range map[string]string{
+		"not json":       `from 1 to 3`,
+		"no range":       `{"workers": 2}`,
+		"backwards":      `{"from": 5, "to": 3}`,
+		"too many":       `{"from": 1, "to": 11}`,
+		"bad workers":    `{"from": 1, "to": 3, "workers": -1}`,
+		"unknown format": `{"from": 1, "to": 3, "format": "xml"}`,
+	} {
+		if resp := postScan(t, server.URL, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/scan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != http.MethodPost {
+		t.Errorf("GET got status %d, allow %q", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+}
+
+func TestServeTurnsAwayScansWhenBusy(t *testing.T) {
+	s, server := newTestScanServer(t)
+
+	// Take the only slot, as a running scan would
+	s.slots <- struct{}{}
+	if resp := postScan(t, server.URL, `{"from": 1, "to": 3}`); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status %d, want 429", resp.StatusCode)
+	}
+
+	<-s.slots
+	if resp := postScan(t, server.URL, `{"from": 1, "to": 3}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d once the slot is free", resp.StatusCode)
+	}
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+
+	// Pure Go driver so the binary still builds without cgo
+	_ "modernc.org/sqlite"
+)
+
+// SQLite stores scan results in a local SQLite database file
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens or creates the database at path and makes sure the table exists
+// Use ":memory:" for a throwaway database
+func OpenSQLite(ctx context.Context, path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite only has a single writer, and every connection to ":memory:" would get its own database
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, createTable); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if err := rebuildTable(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot migrate %s: %w", path, err)
+	}
+
+	return &SQLite{db: db}, nil
+}
+
+// Older files have no chain column, and the very first ones no primary key either, so the upsert has no conflict target
+// SQLite can't change the key of an existing table, it is rebuilt instead and the last inserted of any duplicates is kept
+func rebuildTable(ctx context.Context, db *sql.DB) error {
+	var current int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('balance_changes') WHERE name = 'chain'`).Scan(&current); err != nil {
+		return err
+	}
+	if current > 0 {
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range []string{
+		`ALTER TABLE balance_changes RENAME TO balance_changes_old`,
+		createTable,
+		`INSERT INTO balance_changes (scan_time, block_from, block_to, address, change_wei)
+		SELECT scan_time, block_from, block_to, address, change_wei FROM balance_changes_old
+		WHERE rowid IN (SELECT MAX(rowid) FROM balance_changes_old GROUP BY block_from, block_to, address)`,
+		`DROP TABLE balance_changes_old`,
+	} {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Save writes all balance changes of a scan in one transaction
+func (s *SQLite) Save(ctx context.Context, scan Scan, balances map[string]*big.Int) error {
+	return insertBalances(ctx, s.db, func(int) string { return "?" }, scan, balances)
+}
+
+// Close releases the database handle
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// The tables earlier releases created, the first without a primary key and the second without a chain
+var oldTables = map[string]string{
+	"unkeyed": `CREATE TABLE balance_changes (
+	scan_time  TIMESTAMP NOT NULL,
+	block_from INTEGER   NOT NULL,
+	block_to   INTEGER   NOT NULL,
+	address    TEXT      NOT NULL,
+	change_wei TEXT      NOT NULL
+)`,
+	"no chain": `CREATE TABLE balance_changes (
+	scan_time  TIMESTAMP NOT NULL,
+	block_from BIGINT    NOT NULL,
+	block_to   BIGINT    NOT NULL,
+	address    TEXT      NOT NULL,
+	change_wei TEXT      NOT NULL,
+	PRIMARY KEY (block_from, block_to, address)
+)`,
+}
+
+func TestSQLiteMigratesOldTables(t *testing.T) {
+	for name, table := range oldTables {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "old.db")
+
+			rows := []string{
+				`INSERT INTO balance_changes VALUES ('2020-01-02 00:00:00', 1, 2, '0xa', '2')`,
+				`INSERT INTO balance_changes VALUES ('2020-01-02 00:00:00', 1, 2, '0xb', '3')`,
+			}
+			// Only the table without a key can hold the same address twice
+			if name == "unkeyed" {
+				rows = append([]string{`INSERT INTO balance_changes VALUES ('2020-01-01 00:00:00', 1, 2, '0xa', '1')`}, rows...)
+			}
+
+			old, err := sql.Open("sqlite", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, statement := range append([]string{table}, rows...) {
+				if _, err := old.Exec(statement); err != nil {
+					t.Fatal(err)
+				}
+			}
+			old.Close()
+
+			db, err := OpenSQLite(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			if got := changes(t, db.db, ""); len(got) != 2 || got["0xa"] != "2" || got["0xb"] != "3" {
+				t.Fatalf("after migration got %v, want the last row of every address", got)
+			}
+
+			if err := db.Save(ctx, Scan{Time: time.Now(), From: 1, To: 2}, map[string]*big.Int{"0xa": big.NewInt(5)}); err != nil {
+				t.Fatalf("save into migrated table: %v", err)
+			}
+			if got := changes(t, db.db, ""); got["0xa"] != "5" || got["0xb"] != "3" {
+				t.Fatalf("after save got %v", got)
+			}
+		})
+	}
+}
+
+func TestSQLiteSaveReplacesRange(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenSQLite(ctx, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	scan := Scan{Time: time.Now(), Chain: "mainnet", From: 10, To: 20}
+	if err := db.Save(ctx, scan, map[string]*big.Int{"0xa": big.NewInt(-7), "0xb": big.NewInt(7)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Save(ctx, scan, map[string]*big.Int{"0xa": big.NewInt(-8)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := changes(t, db.db, "mainnet"); len(got) != 2 || got["0xa"] != "-8" || got["0xb"] != "7" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestSQLiteKeepsChainsApart(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenSQLite(ctx, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for chain, change := range map[string]int64{"mainnet": 1, "polygon": 2} {
+		if err := db.Save(ctx, Scan{Time: time.Now(), Chain: chain, From: 10, To: 20}, map[string]*big.Int{"0xa": big.NewInt(change)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := changes(t, db.db, "mainnet"); got["0xa"] != "1" {
+		t.Fatalf("mainnet got %v, the polygon scan of the same range overwrote it", got)
+	}
+	if got := changes(t, db.db, "polygon"); got["0xa"] != "2" {
+		t.Fatalf("polygon got %v", got)
+	}
+}
+
+// Every stored change of chain by address
+func changes(t *testing.T, db *sql.DB, chain string) map[string]string {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT address, change_wei FROM balance_changes WHERE chain = ?`, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	got := map[string]string{}
+	for rows.Next() {
+		var address, change string
+		if err := rows.Scan(&address, &change); err != nil {
+			t.Fatal(err)
+		}
+		got[address] = change
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	return got
+}
+
+func TestSQLiteReadsBackWhatWasSaved(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenSQLite(ctx, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Far more than fits into an int64, the amounts are stored as decimal text
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	scanned := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := db.Save(ctx, Scan{Time: scanned, Chain: "mainnet", From: 100, To: 199}, map[string]*big.Int{"0xa": huge}); err != nil {
+		t.Fatal(err)
+	}
+
+	var from, to int64
+	var stored time.Time
+	var change string
+	err = db.db.QueryRow(`SELECT scan_time, block_from, block_to, change_wei FROM balance_changes WHERE address = '0xa'`).Scan(&stored, &from, &to, &change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Equal(scanned) || from != 100 || to != 199 || change != huge.String() {
+		t.Errorf("read back %s, %d-%d, %s", stored, from, to, change)
+	}
+}
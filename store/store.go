@@ -0,0 +1,90 @@
+// Package store persists scan results into a SQL database so they can be queried later.
+//
+// SQLite and Postgres share the same schema, so queries written against one work on the other.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Store is a database scan results can be written to
+type Store interface {
+	Save(ctx context.Context, scan Scan, balances map[string]*big.Int) error
+	Close() error
+}
+
+// Scan describes the scan a set of balance changes came from
+type Scan struct {
+	Time time.Time
+
+	// The network the blocks came from, the same range on another chain is a different set of rows
+	Chain string
+
+	From int
+	To   int
+}
+
+// Amounts are stored as decimal text since wei values overflow every native integer type
+// Re-scanning the same range of a chain replaces the earlier rows instead of duplicating them
+// Rows saved before the chain was recorded have an empty chain
+const createTable = `CREATE TABLE IF NOT EXISTS balance_changes (
+	scan_time  TIMESTAMP NOT NULL,
+	block_from BIGINT    NOT NULL,
+	block_to   BIGINT    NOT NULL,
+	address    TEXT      NOT NULL,
+	change_wei TEXT      NOT NULL,
+	chain      TEXT      NOT NULL DEFAULT '',
+	PRIMARY KEY (chain, block_from, block_to, address)
+)`
+
+// Rows per INSERT statement, keeps us well below the bind parameter limits of both databases
+const batchSize = 500
+
+// Both SQLite and Postgres understand this upsert, they only differ in placeholder syntax
+const upsert = `INSERT INTO balance_changes (scan_time, chain, block_from, block_to, address, change_wei) VALUES %s
+ON CONFLICT (chain, block_from, block_to, address) DO UPDATE SET scan_time = excluded.scan_time, change_wei = excluded.change_wei`
+
+// Write every balance change of a scan inside a single transaction using multi-row inserts
+// Either the whole scan is persisted or none of it is
+func insertBalances(ctx context.Context, db *sql.DB, placeholder func(n int) string, scan Scan, balances map[string]*big.Int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Insert in a stable order so repeated runs produce identical tables
+	addresses := make([]string, 0, len(balances))
+	for address := range balances {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	for start := 0; start < len(addresses); start += batchSize {
+		end := start + batchSize
+		if end > len(addresses) {
+			end = len(addresses)
+		}
+
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*6)
+
+		for _, address := range addresses[start:end] {
+			n := len(args)
+			values = append(values, fmt.Sprintf("(%s, %s, %s, %s, %s, %s)", placeholder(n+1), placeholder(n+2), placeholder(n+3), placeholder(n+4), placeholder(n+5), placeholder(n+6)))
+			args = append(args, scan.Time.UTC(), scan.Chain, scan.From, scan.To, address, balances[address].String())
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(upsert, strings.Join(values, ", ")), args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
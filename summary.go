@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// Print the activity totals of a scan as a short block of text
+// The cost estimate is only shown with a price per call
+// Without metadata the calls, cost and duration are left out, they differ between runs of the same scan
+func renderSummary(w io.Writer, stats parser.Stats, addresses int, calls int64, costPerCall float64, took time.Duration, currency string, decimals int, checksum bool, metadata bool) {
+	// The average stays exact until the final conversion to a decimal ether amount
+	average := new(big.Int)
+	if stats.Transfers > 0 {
+		average.Quo(stats.Volume, big.NewInt(int64(stats.Transfers)))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Transactions:     %d\n", stats.Transactions)
+	fmt.Fprintf(w, "Value transfers:  %d\n", stats.Transfers)
+	fmt.Fprintf(w, "Volume:           %s %s\n", formatEther(stats.Volume, decimals), currency)
+	fmt.Fprintf(w, "Average transfer: %s %s\n", formatEther(average, decimals), currency)
+	if stats.Burned != nil && stats.Burned.Sign() > 0 {
+		fmt.Fprintf(w, "Burned:           %s %s\n", formatEther(stats.Burned, decimals), currency)
+	}
+	if largest := stats.Largest; largest != nil {
+		display := func(address string) string {
+			if address == "" {
+				return "(contract creation)"
+			}
+			if checksum {
+				return parser.ChecksumAddress(address)
+			}
+			return address
+		}
+
+		fmt.Fprintf(w, "Largest transfer: %s %s in block %d\n", formatEther(largest.Value, decimals), currency, largest.Block)
+		fmt.Fprintf(w, "                  %s -> %s\n", display(largest.From), display(largest.To))
+		fmt.Fprintf(w, "                  tx %s\n", largest.Hash)
+	}
+	fmt.Fprintf(w, "Unique addresses: %d\n", addresses)
+	if !metadata {
+		return
+	}
+	fmt.Fprintf(w, "RPC calls:        %d\n", calls)
+	if costPerCall > 0 {
+		fmt.Fprintf(w, "Estimated cost:   %.4f (%g per call)\n", float64(calls)*costPerCall, costPerCall)
+	}
+	fmt.Fprintf(w, "Duration:         %s\n", took.Round(time.Millisecond))
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+func TestSummaryShowsTheBurn(t *testing.T) {
+	stats := parser.Stats{Volume: new(big.Int), Burned: wei(t, "1500000000000000000")}
+
+	var out strings.Builder
+	renderSummary(&out, stats, 0, 0, 0, time.Second, "ETH", 4, false, false)
+	if !strings.Contains(out.String(), "Burned:           1.5000 ETH\n") {
+		t.Errorf("no burn line\n%s", out.String())
+	}
+
+	// Nothing burned before London, and no line saying so
+	out.Reset()
+	stats.Burned = new(big.Int)
+	renderSummary(&out, stats, 0, 0, 0, time.Second, "ETH", 4, false, false)
+	if strings.Contains(out.String(), "Burned") {
+		t.Errorf("burn line for a zero burn\n%s", out.String())
+	}
+}
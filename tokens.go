@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// A single row of machine readable token output
+// Change is the raw integer amount, Amount is scaled by the token decimals when they are known
+type jsonTokenResult struct {
+	Token    string `json:"token"`
+	Symbol   string `json:"symbol,omitempty"`
+	Decimals *int   `json:"decimals,omitempty"`
+	Holder   string `json:"holder"`
+	Change   string `json:"change"`
+	Amount   string `json:"amount"`
+}
+
+// Group token balances by symbol, then by contract, then by change with the biggest gain first
+func sortTokens(tokens map[parser.TokenHolder]*big.Int, info map[string]parser.TokenInfo) []parser.TokenHolder {
+	keys := make([]parser.TokenHolder, 0, len(tokens))
+
+	for key := range tokens {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		symbolOne, symbolTwo := tokenLabel(keys[i].Token, info), tokenLabel(keys[j].Token, info)
+		if symbolOne != symbolTwo {
+			return symbolOne < symbolTwo
+		}
+		if keys[i].Token != keys[j].Token {
+			return keys[i].Token < keys[j].Token
+		}
+		if c := tokens[keys[i]].Cmp(tokens[keys[j]]); c != 0 {
+			return c > 0
+		}
+		return keys[i].Holder < keys[j].Holder
+	})
+
+	return keys
+}
+
+// Show the symbol when the contract has one, the contract address otherwise
+func tokenLabel(token string, info map[string]parser.TokenInfo) string {
+	if symbol := info[token].Symbol; symbol != "" {
+		return symbol
+	}
+
+	return token
+}
+
+// Format a raw token amount like +1,250.50 using the token decimals
+// Without known decimals the raw integer is shown, still signed and grouped
+func formatTokenAmount(amount *big.Int, decimals int) string {
+	sign := "+"
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+
+	digits := new(big.Int).Abs(amount).String()
+	if decimals <= 0 {
+		return sign + groupThousands(digits)
+	}
+
+	// Left pad so there is always at least one digit before the decimal point
+	for len(digits) <= decimals {
+		digits = "0" + digits
+	}
+
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+
+	// Keep cents for readability, the rest of the trailing zeros add nothing
+	for len(fraction) < 2 && len(fraction) < decimals {
+		fraction += "0"
+	}
+
+	return sign + groupThousands(whole) + "." + fraction
+}
+
+func groupThousands(digits string) string {
+	var grouped strings.Builder
+
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return grouped.String()
+}
+
+// Render the token movements in the requested format, the table says so in words when there are none
+func renderTokens(w io.Writer, format string, result *parser.Result, checksum bool) error {
+	holders, tokens, info := sortTokens(result.Tokens, result.TokenInfo), result.Tokens, result.TokenInfo
+
+	// Same display rule as the ETH report, the map keys stay lowercase
+	display := func(address string) string {
+		if checksum {
+			return parser.ChecksumAddress(address)
+		}
+		return address
+	}
+
+	switch format {
+	case "json", "ndjson":
+		results := make([]jsonTokenResult, 0, len(holders))
+		for _, holder := range holders {
+			token := info[holder.Token]
+			result := jsonTokenResult{
+				Token:  display(holder.Token),
+				Symbol: token.Symbol,
+				Holder: display(holder.Holder),
+				Change: tokens[holder].String(),
+				Amount: formatTokenAmount(tokens[holder], token.Decimals),
+			}
+			if token.Decimals >= 0 {
+				decimals := token.Decimals
+				result.Decimals = &decimals
+			}
+			results = append(results, result)
+		}
+
+		encoder := json.NewEncoder(w)
+
+		if format == "ndjson" {
+			for _, result := range results {
+				if err := encoder.Encode(result); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(results)
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"rank", "token", "symbol", "holder", "amount", "change"})
+
+		for i, holder := range holders {
+			token := info[holder.Token]
+			amount := formatTokenAmount(tokens[holder], token.Decimals)
+			writer.Write([]string{fmt.Sprintf("%d", i+1), display(holder.Token), token.Symbol, display(holder.Holder), amount, tokens[holder].String()})
+		}
+
+		writer.Flush()
+		return writer.Error()
+	case "table":
+		if len(holders) == 0 {
+			_, err := fmt.Fprintln(w, noChanges(result))
+			return err
+		}
+
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{"#", "Token", "Holder", "Total Change"})
+
+		for i, holder := range holders {
+			amount := formatTokenAmount(tokens[holder], info[holder.Token].Decimals)
+			label := tokenLabel(holder.Token, info)
+			if label == holder.Token {
+				label = display(label)
+			}
+			table.Append([]string{fmt.Sprintf("%d", i+1), label, display(holder.Holder), amount})
+		}
+
+		table.Render()
+		return nil
+	default:
+		return fmt.Errorf("-tokens does not support -format %s", format)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+const usdc = "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48"
+
+func TestTokensTableWithoutChanges(t *testing.T) {
+	result := &parser.Result{From: big.NewInt(3), To: big.NewInt(5), Tokens: map[parser.TokenHolder]*big.Int{}}
+
+	var out strings.Builder
+	if err := renderTokens(&out, "table", result, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "No balance changes found in blocks 3–5\n" {
+		t.Errorf("got %q", got)
+	}
+
+	// Machine readable output stays valid and empty
+	out.Reset()
+	if err := renderTokens(&out, "json", result, false); err != nil || out.String() != "[]\n" {
+		t.Errorf("json %q, %v", out.String(), err)
+	}
+}
+
+func TestTokensTable(t *testing.T) {
+	result := &parser.Result{
+		Tokens:    map[parser.TokenHolder]*big.Int{{Token: usdc, Holder: alice}: big.NewInt(-1250500000), {Token: usdc, Holder: bob}: big.NewInt(1250500000)},
+		TokenInfo: map[string]parser.TokenInfo{usdc: {Symbol: "USDC", Decimals: 6}},
+	}
+
+	var out strings.Builder
+	if err := renderTokens(&out, "table", result, false); err != nil {
+		t.Fatal(err)
+	}
+	// The biggest gain comes first, amounts are scaled by the decimals
+	gain, loss := strings.Index(out.String(), "| 1 | USDC  | "+bob), strings.Index(out.String(), "| 2 | USDC  | "+alice)
+	if gain < 0 || loss < gain || !strings.Contains(out.String(), "+1,250.50") || !strings.Contains(out.String(), "-1,250.50") {
+		t.Errorf("got\n%s", out.String())
+	}
+}
+
+func TestTokensRejectOtherFormats(t *testing.T) {
+	for _, format := range []string{"html", "markdown"} {
+		if err := renderTokens(&strings.Builder{}, format, &parser.Result{}, false); err == nil {
+			t.Errorf("%s rendered as something else", format)
+		}
+
+		_, stderr, code := runMain(t, "-rpc-url", "http://127.0.0.1:1", "-tokens", "-format", format)
+		if code != 2 || !strings.Contains(stderr, "-tokens only supports") {
+			t.Errorf("%s: exit code %d\n%s", format, code, stderr)
+		}
+	}
+}
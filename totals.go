@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// The machine readable form of a -count-only report
+type headlineTotals struct {
+	Blocks       int    `json:"blocks"`
+	Transactions int    `json:"transactions"`
+	VolumeWei    string `json:"volume_wei"`
+	VolumeEth    string `json:"volume_eth"`
+	Addresses    int    `json:"unique_addresses"`
+	DurationMs   int64  `json:"duration_ms,omitempty"`
+}
+
+// Render the headline numbers of a -count-only scan, there are no per-address totals to show
+// Without metadata the duration is left out like everywhere else, the text form rounds the volume to decimals places
+func renderCountOnly(w io.Writer, format string, result *parser.Result, took time.Duration, currency string, decimals int, metadata bool) error {
+	totals := headlineTotals{
+		Blocks:       result.Blocks,
+		Transactions: result.Stats.Transactions,
+		VolumeWei:    result.Stats.Volume.String(),
+		VolumeEth:    exactEther(result.Stats.Volume),
+		Addresses:    result.Addresses,
+	}
+	if metadata {
+		totals.DurationMs = took.Milliseconds()
+	}
+
+	switch format {
+	case "json", "ndjson":
+		encoder := json.NewEncoder(w)
+		if format == "json" {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(totals)
+	case "csv":
+		header := []string{"blocks", "transactions", "volume_wei", "volume_eth", "unique_addresses"}
+		record := []string{strconv.Itoa(totals.Blocks), strconv.Itoa(totals.Transactions), totals.VolumeWei, totals.VolumeEth, strconv.Itoa(totals.Addresses)}
+		if metadata {
+			header = append(header, "duration_ms")
+			record = append(record, strconv.FormatInt(totals.DurationMs, 10))
+		}
+
+		writer := csv.NewWriter(w)
+		writer.Write(header)
+		writer.Write(record)
+		writer.Flush()
+		return writer.Error()
+	default:
+		fmt.Fprintf(w, "Blocks:           %d\n", totals.Blocks)
+		fmt.Fprintf(w, "Transactions:     %d\n", totals.Transactions)
+		fmt.Fprintf(w, "Volume:           %s %s\n", formatEther(result.Stats.Volume, decimals), currency)
+		fmt.Fprintf(w, "Unique addresses: %d\n", totals.Addresses)
+		if metadata {
+			fmt.Fprintf(w, "Duration:         %s\n", took.Round(time.Millisecond))
+		}
+		return nil
+	}
+}
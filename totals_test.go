@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+func TestCountOnlyReportMatchesTheFullReport(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+
+	opts := testOptions(t, server.URL)
+	rows := runJSON(t, opts)
+
+	opts.countOnly = true
+	if err := runParser(context.Background(), opts, parser.Config{Workers: 2, CountOnly: true}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(opts.out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var totals headlineTotals
+	if err := json.Unmarshal(data, &totals); err != nil {
+		t.Fatalf("%v in %s", err, data)
+	}
+
+	// Every transfer went to bob, so what he received is the volume
+	want := headlineTotals{Blocks: 10, Transactions: 10, VolumeWei: received(t, rows).String(), VolumeEth: "0.000000000000000955", Addresses: len(rows)}
+	if totals != want {
+		t.Errorf("got %+v, want %+v", totals, want)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ybbus/jsonrpc/v3"
+)
+
+// The header GetBlock reads the API key from
+const apiKeyHeader = "x-api-key"
+
+// A JSON-RPC client for endpoint on httpClient, sending key when there is one
+func newRPCClient(endpoint, key string, httpClient *http.Client) jsonrpc.RPCClient {
+	opts := &jsonrpc.RPCClientOpts{HTTPClient: httpClient}
+	if key != "" {
+		opts.CustomHeaders = map[string]string{apiKeyHeader: key}
+	}
+
+	return jsonrpc.NewClientWithOpts(endpoint, opts)
+}
+
+// Build the HTTP client all RPC requests go out on
+// Without -proxy the standard HTTP_PROXY, HTTPS_PROXY and NO_PROXY variables decide, like for any Go program
+// A -ca-cert is trusted on top of the system roots, for proxies that inspect TLS with their own certificate
+// The timeout also covers requests made outside a scan, like the first eth_blockNumber
+func newHTTPClient(proxy, caCert string, timeout time.Duration) (*http.Client, *websocket.Dialer, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, nil, fmt.Errorf("invalid -proxy %q, expected a URL like http://proxy.example.com:3128", proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if caCert != "" {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot read -ca-cert: %w", err)
+		}
+
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no PEM certificates found in -ca-cert %s", caCert)
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+
+	// The WebSocket for -ws-url goes through the same proxy and trusts the same certificates
+	dialer := &websocket.Dialer{
+		Proxy:            transport.Proxy,
+		TLSClientConfig:  transport.TLSClientConfig,
+		HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+	}
+
+	return &http.Client{Transport: transport, Timeout: timeout}, dialer, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Counts the requests that went through it before handing them on
+type countingTransport struct {
+	requests atomic.Int32
+	keys     atomic.Value
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	c.keys.Store(r.Header.Get(apiKeyHeader))
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestRequestsGoThroughTheGivenClient(t *testing.T) {
+	_, server := newFakeNode(t, 100)
+	transport := &countingTransport{}
+
+	client := newRPCClient(server.URL, "secret", &http.Client{Transport: transport})
+	if _, err := latestBlock(context.Background(), client, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := transport.requests.Load(); got != 1 {
+		t.Errorf("%d requests through the transport, want 1", got)
+	}
+	if key := transport.keys.Load(); key != "secret" {
+		t.Errorf("key %q sent, want the one given", key)
+	}
+}
+
+func TestProxyCarriesTheRequests(t *testing.T) {
+	// The fake node plays the proxy, a proxied request reaches it whatever host it is for
+	node, proxy := newFakeNode(t, 100)
+
+	httpClient, _, err := newHTTPClient(proxy.URL, "", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := latestBlock(context.Background(), newRPCClient("http://rpc.example.invalid", "", httpClient), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Int64() != 100 {
+		t.Errorf("head %v, want 100", head)
+	}
+
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if len(node.methods) != 1 {
+		t.Errorf("proxy saw %v", node.methods)
+	}
+}
+
+func TestCACertIsTrusted(t *testing.T) {
+	node := &fakeNode{head: 100}
+	server := httptest.NewTLSServer(node)
+	t.Cleanup(server.Close)
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caCert, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The test server's certificate is self-signed, only -ca-cert makes it trusted
+	plain, _, err := newHTTPClient("", "", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := latestBlock(context.Background(), newRPCClient(server.URL, "", plain), false); err == nil {
+		t.Error("untrusted certificate accepted")
+	}
+
+	trusting, dialer, err := newHTTPClient("", caCert, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := latestBlock(context.Background(), newRPCClient(server.URL, "", trusting), false); err != nil {
+		t.Errorf("trusted certificate refused: %v", err)
+	}
+	if dialer.TLSClientConfig == nil || dialer.TLSClientConfig.RootCAs == nil {
+		t.Error("the WebSocket dialer does not trust the certificate")
+	}
+}
+
+func TestBadTransportSettings(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, settings := range map[string][2]string{
+		"proxy without host": {"proxy.example.com", ""},
+		"proxy not a URL":    {"http://[::1", ""},
+		"missing ca-cert":    {"", filepath.Join(t.TempDir(), "missing.pem")},
+		"ca-cert not PEM":    {"", notPEM},
+	} {
+		if _, _, err := newHTTPClient(settings[0], settings[1], time.Second); err == nil {
+			t.Errorf("%s accepted", name)
+		}
+	}
+}
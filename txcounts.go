@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// The machine readable form of a -tx-counts-only report
+type txCounts struct {
+	Blocks       int     `json:"blocks"`
+	Transactions int     `json:"transactions"`
+	PerBlock     float64 `json:"transactions_per_block"`
+}
+
+// Render the transaction count of a -tx-counts-only scan, which is all such a scan has
+func renderTxCounts(w io.Writer, format string, result *parser.Result, took time.Duration, metadata bool) error {
+	counts := txCounts{Blocks: result.Blocks, Transactions: result.Stats.Transactions}
+	if counts.Blocks > 0 {
+		counts.PerBlock = float64(counts.Transactions) / float64(counts.Blocks)
+	}
+
+	switch format {
+	case "json", "ndjson":
+		encoder := json.NewEncoder(w)
+		if format == "json" {
+			encoder.SetIndent("", "  ")
+		}
+		return encoder.Encode(counts)
+	case "csv":
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"blocks", "transactions", "transactions_per_block"})
+		writer.Write([]string{strconv.Itoa(counts.Blocks), strconv.Itoa(counts.Transactions), strconv.FormatFloat(counts.PerBlock, 'f', 2, 64)})
+		writer.Flush()
+		return writer.Error()
+	default:
+		fmt.Fprintf(w, "Blocks:           %d\n", counts.Blocks)
+		fmt.Fprintf(w, "Transactions:     %d\n", counts.Transactions)
+		fmt.Fprintf(w, "Per block:        %.2f\n", counts.PerBlock)
+		if metadata {
+			fmt.Fprintf(w, "Duration:         %s\n", took.Round(time.Millisecond))
+		}
+		return nil
+	}
+}
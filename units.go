@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/samsheff/getblocktz/report"
+)
+
+// Ether has 18 decimal places of wei
+const etherDecimals = 18
+
+// Resolve a -unit name, eth is labelled with the chain's own currency
+// Wei and gwei are formatted exactly, eth is rounded to the given number of decimal places
+func lookupUnit(name, currency string, decimals int) (report.Unit, error) {
+	switch name {
+	case "wei":
+		return report.Unit{Label: "wei", Format: func(wei *big.Int) string { return wei.String() }}, nil
+	case "gwei":
+		return report.Unit{Label: "gwei", Format: func(wei *big.Int) string { return scaleDecimal(wei, 9) }}, nil
+	case "eth":
+		return report.Unit{Label: currency, Format: func(wei *big.Int) string { return formatEther(wei, decimals) }}, nil
+	}
+
+	return report.Unit{}, fmt.Errorf("unknown -unit %q, expected wei, gwei or eth", name)
+}
+
+// A wei amount in ether without any rounding, for machine readable fields that sit next to the wei
+func exactEther(wei *big.Int) string {
+	return scaleDecimal(wei, etherDecimals)
+}
+
+// A wei amount in ether with exactly decimals places, for everything a person reads
+func formatEther(wei *big.Int, decimals int) string {
+	return roundDecimal(wei, etherDecimals, decimals)
+}
+
+// Divide by 10^scale and round to places decimals, half to even, places can't be more than scale
+// The rounding is done on the integer so a tie is a real tie, a float quotient could land on either side of it
+// Unlike scaleDecimal the result always has places decimals so the column lines up
+func roundDecimal(amount *big.Int, scale, places int) string {
+	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale-places)), nil)
+	quotient, remainder := new(big.Int).QuoRem(new(big.Int).Abs(amount), divisor, new(big.Int))
+
+	// Over half rounds up, exactly half only when that makes the last digit even
+	switch remainder.Lsh(remainder, 1).Cmp(divisor) {
+	case 1:
+		quotient.Add(quotient, big.NewInt(1))
+	case 0:
+		if quotient.Bit(0) == 1 {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	sign := ""
+	if amount.Sign() < 0 && quotient.Sign() > 0 {
+		sign = "-"
+	}
+
+	digits := quotient.String()
+	for len(digits) <= places {
+		digits = "0" + digits
+	}
+	if places == 0 {
+		return sign + digits
+	}
+
+	return sign + digits[:len(digits)-places] + "." + digits[len(digits)-places:]
+}
+
+// Divide by 10^decimals using string arithmetic so no precision is lost
+func scaleDecimal(amount *big.Int, decimals int) string {
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+
+	digits := new(big.Int).Abs(amount).String()
+	for len(digits) <= decimals {
+		digits = "0" + digits
+	}
+
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+
+	return sign + whole + "." + fraction
+}
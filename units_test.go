@@ -0,0 +1,121 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/samsheff/getblocktz/report"
+)
+
+func wei(t *testing.T, s string) *big.Int {
+	t.Helper()
+
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		t.Fatalf("bad wei amount %q", s)
+	}
+	return n
+}
+
+func TestFormatEtherRoundsHalfToEven(t *testing.T) {
+	tests := []struct {
+		wei      string
+		decimals int
+		want     string
+	}{
+		// 1.2345675 ETH is a tie at 6 decimals, the 7 is odd so it rounds up
+		{"1234567500000000000", 6, "1.234568"},
+		{"1234567500000000000", 5, "1.23457"},
+		{"1234567500000000000", 2, "1.23"},
+		{"1234567500000000000", 0, "1"},
+		{"1234567500000000000", 18, "1.234567500000000000"},
+		// A tie on an even digit stays put
+		{"1234566500000000000", 6, "1.234566"},
+		{"2500000000000000000", 0, "2"},
+		{"3500000000000000000", 0, "4"},
+		// Just over the tie always goes up
+		{"1234566500000000001", 6, "1.234567"},
+		{"-1234567500000000000", 6, "-1.234568"},
+		{"-2500000000000000000", 0, "-2"},
+		// Too small to show at all, without a minus sign on zero
+		{"400000000000", 6, "0.000000"},
+		{"-1", 6, "0.000000"},
+		{"1500000000000", 6, "0.000002"},
+		{"0", 2, "0.00"},
+		// Far beyond what a float64 holds exactly
+		{"123456789012345678901234567890", 6, "123456789012.345679"},
+	}
+
+	for _, tt := range tests {
+		if got := formatEther(wei(t, tt.wei), tt.decimals); got != tt.want {
+			t.Errorf("formatEther(%s, %d) = %s, want %s", tt.wei, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestExactEther(t *testing.T) {
+	tests := map[string]string{
+		"0":                              "0",
+		"1":                              "0.000000000000000001",
+		"-1500000000000000000":           "-1.5",
+		"1000000000000000000":            "1",
+		"123456789012345678901234567891": "123456789012.345678901234567891",
+	}
+
+	for in, want := range tests {
+		if got := exactEther(wei(t, in)); got != want {
+			t.Errorf("exactEther(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestLookupUnitUsesDecimals(t *testing.T) {
+	u, err := lookupUnit("eth", "ETH", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Format(wei(t, "1234500000000000000")); got != "1.234" {
+		t.Errorf("eth with 3 decimals got %s", got)
+	}
+
+	// Wei and gwei stay exact whatever -decimals says
+	u, _ = lookupUnit("gwei", "ETH", 3)
+	if got := u.Format(wei(t, "1234567891")); got != "1.234567891" {
+		t.Errorf("gwei got %s", got)
+	}
+
+	if _, err := lookupUnit("finney", "ETH", 6); err == nil {
+		t.Error("unknown unit accepted")
+	}
+}
+
+// The same amount in each unit, both the figure and the column header that names it
+func TestOneAmountInEveryUnit(t *testing.T) {
+	amount := wei(t, "1234567891234567891")
+	rows := []report.Row{{Address: "0x000000000000000000000000000000000000000a", Change: amount, Sent: big.NewInt(0), Received: amount, TxCount: 1}}
+
+	for _, tt := range []struct {
+		unit   string
+		want   string
+		header string
+	}{
+		{"wei", "1234567891234567891", "TOTAL CHANGE (WEI)"},
+		{"gwei", "1234567891.234567891", "TOTAL CHANGE (GWEI)"},
+		{"eth", "1.2346", "TOTAL CHANGE (ETH)"},
+	} {
+		u, err := lookupUnit(tt.unit, "ETH", 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := u.Format(amount); got != tt.want {
+			t.Errorf("%s shows %s, want %s", tt.unit, got, tt.want)
+		}
+
+		var out strings.Builder
+		renderTable(&out, rows, u, nil)
+		if !strings.Contains(out.String(), tt.header) || !strings.Contains(out.String(), tt.want) {
+			t.Errorf("%s table lacks %q or %s\n%s", tt.unit, tt.header, tt.want, out.String())
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, for example
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Whatever is left empty is taken from the build info the go command embeds
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// The line printed by -version, like "getblocktz v1.2.0 (commit 1a2b3c4, built 2024-05-01T10:00:00Z, go1.22.2)"
+func versionString() string {
+	v, c, d := version, commit, date
+	dirty := false
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" {
+			v = info.Main.Version
+		}
+
+		// The build info only describes the checkout when none of it came in through -ldflags
+		if commit == "" {
+			for _, setting := range info.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					c = setting.Value
+				case "vcs.time":
+					if d == "" {
+						d = setting.Value
+					}
+				case "vcs.modified":
+					dirty = setting.Value == "true"
+				}
+			}
+		}
+	}
+
+	if v == "" {
+		v = "(devel)"
+	}
+	if len(c) > 12 {
+		c = c[:12]
+	}
+	if c == "" {
+		c = "unknown"
+	} else if dirty {
+		c += "-dirty"
+	}
+	if d == "" {
+		d = "unknown"
+	}
+
+	return fmt.Sprintf("getblocktz %s (commit %s, built %s, %s)", v, c, d, runtime.Version())
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestVersionStringFromLdflags(t *testing.T) {
+	defer func(v, c, d string) { version, commit, date = v, c, d }(version, commit, date)
+	version, commit, date = "v1.2.0", "1a2b3c4d5e6f7a8b9c0d", "2024-05-01T10:00:00Z"
+
+	want := "getblocktz v1.2.0 (commit 1a2b3c4d5e6f, built 2024-05-01T10:00:00Z, " + runtime.Version() + ")"
+	if got := versionString(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestVersionStringWithoutLdflags(t *testing.T) {
+	defer func(v, c, d string) { version, commit, date = v, c, d }(version, commit, date)
+	version, commit, date = "", "", ""
+
+	// A test binary has no version or checkout of its own, every part still gets filled in
+	got := versionString()
+	if !regexp.MustCompile(`^getblocktz \S+ \(commit \S+, built \S+, go\S+\)$`).MatchString(got) {
+		t.Errorf("got %q", got)
+	}
+	if strings.Contains(got, "  ") || strings.Contains(got, "()") {
+		t.Errorf("empty part in %q", got)
+	}
+}
+
+func TestVersionFlagPrintsAndExits(t *testing.T) {
+	stdout, _, code := runMain(t, "-version")
+	if code != 0 || !strings.HasPrefix(stdout, "getblocktz ") || strings.Count(stdout, "\n") != 1 {
+		t.Errorf("exit %d with %q", code, stdout)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/ofen/getblock-go/eth"
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// A block near the head together with what it added to the totals, so a reorg can take it back out
+type tentativeBlock struct {
+	header parser.Header
+	result *parser.Result
+}
+
+// Follow new heads after the initial scan and fold each new block into the running totals
+// Blocks within opts.reorgDepth of the head are tentative, when a new block doesn't build on the last one
+// the last one is rolled back and fetched again until the chain lines up
+// Every time the totals change they are reported again, until the context is cancelled
+func watch(ctx context.Context, opts options, client *eth.Client, config parser.Config, lookup *lookups, head *big.Int, last *big.Int, totals *parser.Result, start time.Time) error {
+	// Increments are tiny, so a checkpoint or a progress bar would only add noise
+	config.Checkpoint = ""
+	config.Progress = nil
+
+	var tentative []tentativeBlock
+
+	// The newest block of the backfill is final, new blocks have to build on it
+	var final parser.Header
+	if n := len(totals.Headers); n > 0 && totals.Headers[n-1].Number.Cmp(last) == 0 {
+		final = totals.Headers[n-1]
+	}
+
+	// The backfill stopped short of the head, so start with the blocks it left out
+	heads := make(chan *big.Int, 1)
+	heads <- head
+	go followHeads(ctx, opts, client, heads)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case head = <-heads:
+		}
+
+		if head.Cmp(last) <= 0 {
+			continue
+		}
+
+		config.CacheFinalized = new(big.Int).Sub(head, cacheReorgDepth)
+		changed := false
+
+		// One block at a time, so each one's contribution can be undone on its own
+		for next := new(big.Int).Add(last, big.NewInt(1)); next.Cmp(head) <= 0; {
+			result, err := parser.Scan(ctx, client.Client, next, next, config)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			// The failure is logged already, the next head tries this block again
+			if len(result.Headers) == 0 {
+				break
+			}
+
+			header := result.Headers[0]
+
+			if n := len(tentative); n > 0 && tentative[n-1].header.Hash != header.ParentHash {
+				orphan := tentative[n-1]
+				slog.Warn("reorg detected, rolling back block", "block", orphan.header.Number, "hash", orphan.header.Hash)
+
+				// Neither block counts any more, both get fetched again from the new canonical chain
+				totals.Subtract(orphan.result)
+				config.Seen.Remove(orphan.header.Number)
+				config.Seen.Remove(next)
+
+				tentative = tentative[:n-1]
+				next = orphan.header.Number
+				last = new(big.Int).Sub(next, big.NewInt(1))
+				changed = true
+				continue
+			}
+
+			if len(tentative) == 0 && final.Hash != "" && final.Hash != header.ParentHash {
+				slog.Warn("reorg deeper than -reorg-depth, totals may include orphaned blocks", "block", header.Number)
+			}
+
+			totals.Merge(result)
+			tentative = append(tentative, tentativeBlock{header: header, result: result})
+			last = header.Number
+			next = new(big.Int).Add(next, big.NewInt(1))
+			changed = true
+		}
+
+		// Blocks that fell out of the window are final, only those are written to the databases
+		finalized := new(big.Int).Sub(head, big.NewInt(int64(opts.reorgDepth)))
+		for len(tentative) > 0 && tentative[0].header.Number.Cmp(finalized) <= 0 {
+			block := tentative[0]
+			if err := saveResults(ctx, opts, block.header.Number, block.header.Number, block.result.Balances); err != nil {
+				slog.Warn("cannot save results", "err", err)
+			}
+
+			final = block.header
+			tentative = tentative[1:]
+		}
+
+		if !changed {
+			continue
+		}
+
+		slog.Info("totals updated", "head", last, "tentative", len(tentative))
+
+		if err := printReport(ctx, opts, lookup, totals, time.Since(start)); err != nil {
+			return err
+		}
+	}
+}
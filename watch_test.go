@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/samsheff/getblocktz/parser"
+)
+
+// The rows of a running watch's report, nil while it hasn't been written or is half written
+func watchRows(t *testing.T, path string) []jsonResult {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var rows []jsonResult
+	if json.Unmarshal(data, &rows) != nil {
+		return nil
+	}
+	return rows
+}
+
+// Bob's total in the report of a running watch
+func watchReport(t *testing.T, path string) *big.Int {
+	t.Helper()
+
+	for _, row := range watchRows(t, path) {
+		if row.Address == bob {
+			change, _ := new(big.Int).SetString(row.ChangeWei, 10)
+			return change
+		}
+	}
+	return nil
+}
+
+// Wait until bob's total in the report of a running watch is want
+func waitForTotal(t *testing.T, path string, want *big.Int) {
+	t.Helper()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		if got := watchReport(t, path); got != nil && got.Cmp(want) == 0 {
+			return
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("bob's total never reached %s, last report has %v", want, watchReport(t, path))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// The sum of the block numbers from..to, what bob gets from those blocks
+func blockSum(from, to int64) *big.Int {
+	total := big.NewInt(0)
+	for n := from; n <= to; n++ {
+		total.Add(total, big.NewInt(n))
+	}
+	return total
+}
+
+func TestWatchFollowsAnAdvancingHead(t *testing.T) {
+	node, server := newFakeNode(t, 100)
+	node.top = 105
+
+	opts := testOptions(t, server.URL)
+	opts.watch = true
+	opts.pollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- runParser(ctx, opts, parser.Config{Workers: 2}) }()
+
+	// The backfill covers 91 to 100, the watch adds 101 to 105 one at a time
+	waitForTotal(t, opts.out, blockSum(91, 105))
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// Every block once, the new ones only after the backfill
+	seen := map[uint64]bool{}
+	for _, block := range node.fetched() {
+		if seen[block] {
+			t.Errorf("block %d fetched twice", block)
+		}
+		seen[block] = true
+	}
+}
+
+func TestWatchRollsBackAReorg(t *testing.T) {
+	node, server := newFakeNode(t, 100)
+	node.top = 102
+	node.extra = map[uint64]bool{101: true, 102: true}
+
+	opts := testOptions(t, server.URL)
+	opts.watch = true
+	opts.pollInterval = 10 * time.Millisecond
+	opts.reorgDepth = 3
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- runParser(ctx, opts, parser.Config{Workers: 2}) }()
+
+	waitForTotal(t, opts.out, blockSum(91, 102))
+	if rows := watchRows(t, opts.out); len(rows) != 4 {
+		t.Fatalf("report has %+v, want carol and dave from blocks 101 and 102 as well", rows)
+	}
+
+	// Blocks 101 and 102 get replaced and 103 builds on the new ones
+	node.mu.Lock()
+	node.versions = map[uint64]uint64{101: 1, 102: 1, 103: 1}
+	node.top = 103
+	node.mu.Unlock()
+
+	// The orphans are gone and the canonical blocks count, each 1000 wei more than the block it replaced
+	want := blockSum(91, 103)
+	want.Add(want, big.NewInt(3000))
+	waitForTotal(t, opts.out, want)
+
+	// Carol and dave were only in the orphans, so like in a fresh scan of the canonical chain they have no row
+	if rows := watchRows(t, opts.out); len(rows) != 2 {
+		t.Errorf("report has %+v, want only alice and bob", rows)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}